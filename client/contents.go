@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"pm/models"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the file or branch changed on GitHub after it was
+	// read.
+	ErrConflict = errors.New("file was changed by someone else")
+)
+
+func contentsURL(owner, repo, path string) string {
+	return fmt.Sprintf("%s/repos/%s/%s/contents/%s", githubAPI, owner, repo, (&neturl.URL{Path: path}).EscapedPath())
+}
+
+// GetRepoContent reads a file at ref (a branch, tag or SHA; empty for the
+// default branch). A missing file returns ErrNotFound.
+func GetRepoContent(token, owner, repo, path, ref string) (models.RepoContent, error) {
+	url := contentsURL(owner, repo, path)
+	if ref != "" {
+		url += "?ref=" + neturl.QueryEscape(ref)
+	}
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return models.RepoContent{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.RepoContent{}, responseError(resp, path)
+	}
+	var content models.RepoContent
+	if err := json.NewDecoder(resp.Body).Decode(&content); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// responseError turns a failed API response into an error, using the message
+// GitHub sends back.
+func responseError(resp *http.Response, path string) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	case http.StatusConflict:
+		return fmt.Errorf("%s: %w", path, ErrConflict)
+	case http.StatusUnprocessableEntity:
+		// Fast-forwarding a branch that moved since it was read.
+		if strings.Contains(body.Message, "not a fast forward") {
+			return fmt.Errorf("%s: %w", path, ErrConflict)
+		}
+	}
+	if body.Message == "" {
+		body.Message = resp.Status
+	}
+	return fmt.Errorf("%s: GitHub API error: %s", path, body.Message)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"fmt"
+	neturl "net/url"
+	"pm/models"
+)
+
+// GetCommitTree returns the tree a commit points at.
+func GetCommitTree(token, owner, repo, sha string) (string, error) {
+	var commit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/git/commits/%s", githubAPI, owner, repo, sha)
+	if err := sendJSON(token, "GET", url, nil, &commit, "commit "+sha); err != nil {
+		return "", err
+	}
+	return commit.Tree.SHA, nil
+}
+
+// CreateTree creates a tree of baseTree with entries added or replaced and
+// returns its SHA.
+func CreateTree(token, owner, repo, baseTree string, entries []models.TreeEntry) (string, error) {
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/git/trees", githubAPI, owner, repo)
+	body := map[string]any{"base_tree": baseTree, "tree": entries}
+	if err := sendJSON(token, "POST", url, body, &tree, "tree"); err != nil {
+		return "", err
+	}
+	return tree.SHA, nil
+}
+
+// CreateCommit creates a commit without moving any branch to it and returns
+// its SHA.
+func CreateCommit(token, owner, repo string, commit models.NewCommit) (string, error) {
+	var created struct {
+		SHA string `json:"sha"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/git/commits", githubAPI, owner, repo)
+	if err := sendJSON(token, "POST", url, commit, &created, "commit"); err != nil {
+		return "", err
+	}
+	return created.SHA, nil
+}
+
+// UpdateBranch fast-forwards branch to sha. It fails with ErrConflict when sha
+// does not descend from the branch's head, as when someone pushed since.
+func UpdateBranch(token, owner, repo, branch, sha string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", githubAPI, owner, repo, (&neturl.URL{Path: branch}).EscapedPath())
+	body := map[string]any{"sha": sha, "force": false}
+	return sendJSON(token, "PATCH", url, body, nil, "branch "+branch)
+}
@@ -4,11 +4,58 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	neturl "net/url"
+	"path"
 	"pm/models"
+	"strings"
 	"time"
 )
 
-const githubAPI = "https://api.github.com"
+var githubAPI = "https://api.github.com"
+
+var (
+	includeRepos []string
+	excludeRepos []string
+)
+
+// SetHost points the client at github.com or a GitHub Enterprise host.
+func SetHost(host string) {
+	if host == "" || host == "github.com" {
+		githubAPI = "https://api.github.com"
+		return
+	}
+	githubAPI = fmt.Sprintf("https://%s/api/v3", host)
+}
+
+// SetAPIURL points the client at an API root URL, such as a test server's.
+func SetAPIURL(url string) {
+	githubAPI = strings.TrimSuffix(url, "/")
+}
+
+// SetRepoFilter restricts GetUserRepos to repos whose full name matches one of
+// include (when non-empty) and none of exclude, using path.Match patterns.
+func SetRepoFilter(include, exclude []string) {
+	includeRepos = include
+	excludeRepos = exclude
+}
+
+// RepoAllowed reports whether fullName passes the filter set by SetRepoFilter.
+func RepoAllowed(fullName string) bool {
+	for _, pattern := range excludeRepos {
+		if ok, _ := path.Match(pattern, fullName); ok {
+			return false
+		}
+	}
+	if len(includeRepos) == 0 {
+		return true
+	}
+	for _, pattern := range includeRepos {
+		if ok, _ := path.Match(pattern, fullName); ok {
+			return true
+		}
+	}
+	return false
+}
 
 func GetUserRepos(token string, since time.Time) ([]models.GithubRepo, error) {
 	url := fmt.Sprintf("%s/user/repos?per_page=100", githubAPI)
@@ -34,6 +81,9 @@ func GetUserRepos(token string, since time.Time) ([]models.GithubRepo, error) {
 		if repo.Owner.Login == repo.Name {
 			continue
 		}
+		if !RepoAllowed(repo.FullName) {
+			continue
+		}
 		updatedAt, err := time.Parse(time.RFC3339, repo.UpdatedAt)
 		if err == nil && updatedAt.After(since) {
 			filteredRepos = append(filteredRepos, repo)
@@ -42,53 +92,6 @@ func GetUserRepos(token string, since time.Time) ([]models.GithubRepo, error) {
 	return filteredRepos, nil
 }
 
-func GetUserMergedPRs(token, owner, repo string, since time.Time) ([]models.PullRequest, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=closed&per_page=100", githubAPI, owner, repo)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var allPRs []models.PullRequest
-	if err := json.NewDecoder(resp.Body).Decode(&allPRs); err != nil {
-		return nil, err
-	}
-
-	var filtered []models.PullRequest
-	for _, pr := range allPRs {
-		if pr.MergedAt == "" {
-			continue
-		}
-		mergedAt, err := time.Parse(time.RFC3339, pr.MergedAt)
-		if err != nil || mergedAt.Before(since) {
-			continue
-		}
-		// Fetch detailed PR info
-		detailURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPI, owner, repo, pr.Number)
-		detailReq, _ := http.NewRequest("GET", detailURL, nil)
-		detailReq.Header.Set("Authorization", "Bearer "+token)
-		detailReq.Header.Set("Accept", "application/vnd.github+json")
-		detailResp, err := client.Do(detailReq)
-		if err != nil {
-			continue
-		}
-		defer detailResp.Body.Close()
-
-		var detailedPR models.PullRequest
-		if err := json.NewDecoder(detailResp.Body).Decode(&detailedPR); err != nil {
-			continue
-		}
-		filtered = append(filtered, detailedPR)
-	}
-	return filtered, nil
-}
-
 func GetRepoLanguages(token, owner, repo string) (map[string]int, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/languages", githubAPI, owner, repo)
 	req, _ := http.NewRequest("GET", url, nil)
@@ -110,7 +113,7 @@ func GetRepoLanguages(token, owner, repo string) (map[string]int, error) {
 }
 
 func GetGitHubUsername(token string) (string, error) {
-	req, _ := http.NewRequest("GET", "https://api.github.com/user", nil)
+	req, _ := http.NewRequest("GET", githubAPI+"/user", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
@@ -130,23 +133,66 @@ func GetGitHubUsername(token string) (string, error) {
 	return user.Login, nil
 }
 
-func GetUserCommits(token, owner, repo, username string, since time.Time) (int, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits?author=%s&since=%s", owner, repo, username, since.Format(time.RFC3339))
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
+// searchPages caps GetUserReviewedPRs at the 1000 results the search API
+// returns.
+const searchPages = 10
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
+// GetUserReviewedPRs lists pull requests by others that username reviewed and
+// that were updated since the given time. total is the search's full count,
+// which may exceed the pull requests returned.
+func GetUserReviewedPRs(token, username string, since time.Time) (prs []models.ReviewedPR, total int, err error) {
+	return searchReviewedPRs(token, username, ">="+since.Format("2006-01-02"))
+}
+
+// GetUserReviewedPRsBetween is GetUserReviewedPRs for pull requests last
+// updated in [since, until), to the second.
+func GetUserReviewedPRsBetween(token, username string, since, until time.Time) (prs []models.ReviewedPR, total int, err error) {
+	return searchReviewedPRs(token, username, since.UTC().Format(time.RFC3339)+".."+until.Add(-time.Second).UTC().Format(time.RFC3339))
+}
 
-	var commits []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
-		return 0, err
+// searchReviewedPRs runs the reviewed pull request search with the given
+// updated: qualifier.
+func searchReviewedPRs(token, username, updated string) (prs []models.ReviewedPR, total int, err error) {
+	query := fmt.Sprintf("type:pr reviewed-by:%s -author:%s updated:%s", username, username, updated)
+	for page := 1; page <= searchPages; page++ {
+		var result struct {
+			TotalCount int                 `json:"total_count"`
+			Items      []models.ReviewedPR `json:"items"`
+		}
+		url := fmt.Sprintf("%s/search/issues?per_page=100&page=%d&q=%s", githubAPI, page, neturl.QueryEscape(query))
+		if err := sendJSON(token, "GET", url, nil, &result, "reviewed pull requests"); err != nil {
+			return prs, total, err
+		}
+		total = result.TotalCount
+		prs = append(prs, result.Items...)
+		if len(result.Items) < 100 || len(prs) >= total {
+			break
+		}
 	}
+	return prs, total, nil
+}
 
-	return len(commits), nil
+// GetFirstReviewTime returns when username first reviewed pull request
+// number of repo ("owner/name"), or "" if they left no review.
+func GetFirstReviewTime(token, repo string, number int, username string) (string, error) {
+	first := ""
+	for page := 1; ; page++ {
+		var reviews []struct {
+			User        models.GithubUser `json:"user"`
+			SubmittedAt string            `json:"submitted_at"`
+		}
+		url := fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?per_page=%d&page=%d", githubAPI, repo, number, perPage, page)
+		if err := sendJSON(token, "GET", url, nil, &reviews, fmt.Sprintf("reviews of %s#%d", repo, number)); err != nil {
+			return "", err
+		}
+		for _, review := range reviews {
+			// RFC 3339 UTC timestamps sort as strings.
+			if review.User.Login == username && review.SubmittedAt != "" && (first == "" || review.SubmittedAt < first) {
+				first = review.SubmittedAt
+			}
+		}
+		if len(reviews) < perPage {
+			return first, nil
+		}
+	}
 }
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"pm/models"
+	"time"
+)
+
+// GetBranchSHA returns the commit a branch points at. A missing branch returns
+// ErrNotFound.
+func GetBranchSHA(token, owner, repo, branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/git/ref/heads/%s", githubAPI, owner, repo, (&neturl.URL{Path: branch}).EscapedPath())
+	if err := sendJSON(token, "GET", url, nil, &ref, "branch "+branch); err != nil {
+		return "", err
+	}
+	return ref.Object.SHA, nil
+}
+
+func CreateBranch(token, owner, repo, branch, sha string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs", githubAPI, owner, repo)
+	body := map[string]string{"ref": "refs/heads/" + branch, "sha": sha}
+	return sendJSON(token, "POST", url, body, nil, "branch "+branch)
+}
+
+// FindPullRequest returns the open pull request from head (owner:branch) into
+// base, if any.
+func FindPullRequest(token, owner, repo, head, base string) (models.PullRequest, bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s&base=%s", githubAPI, owner, repo, neturl.QueryEscape(head), neturl.QueryEscape(base))
+	var prs []models.PullRequest
+	if err := sendJSON(token, "GET", url, nil, &prs, "pull requests"); err != nil {
+		return models.PullRequest{}, false, err
+	}
+	if len(prs) == 0 {
+		return models.PullRequest{}, false, nil
+	}
+	return prs[0], true, nil
+}
+
+func CreatePullRequest(token, owner, repo string, pr models.NewPullRequest) (models.PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls", githubAPI, owner, repo)
+	var created models.PullRequest
+	err := sendJSON(token, "POST", url, pr, &created, "pull request")
+	return created, err
+}
+
+func UpdatePullRequest(token, owner, repo string, number int, pr models.NewPullRequest) (models.PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPI, owner, repo, number)
+	var updated models.PullRequest
+	err := sendJSON(token, "PATCH", url, pr, &updated, fmt.Sprintf("pull request #%d", number))
+	return updated, err
+}
+
+// sendJSON sends body as JSON and decodes a successful response into out;
+// what names the resource in errors. A rate limited request is retried once
+// the limit resets, if that is soon.
+func sendJSON(token, method, url string, body, out any, what string) error {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	client := &http.Client{}
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(encoded))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		var err error
+		if resp, err = client.Do(req); err != nil {
+			return err
+		}
+		wait, limited := rateLimitWait(resp, time.Now())
+		if !limited || attempt == rateLimitRetries {
+			break
+		}
+		resp.Body.Close()
+		log.Printf("⏳ GitHub rate limit reached fetching %s, retrying in %s", what, wait)
+		time.Sleep(wait)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp, what)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
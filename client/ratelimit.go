@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// rateLimitRetries is how many times sendJSON waits out a rate limit.
+	rateLimitRetries = 3
+	// maxRateLimitWait is the longest sendJSON waits for a limit to reset;
+	// the search API's resets every minute, the core API's can take an hour.
+	maxRateLimitWait = 2 * time.Minute
+)
+
+// rateLimitWait reports whether resp was rate limited and, if so, how long
+// to wait before retrying: Retry-After for secondary limits, or until
+// X-RateLimit-Reset once the limit is used up. Limits that reset later than
+// maxRateLimitWait are not waited for.
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	wait := time.Duration(-1)
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait = max(time.Unix(reset, 0).Sub(now), 0) + time.Second
+		}
+	}
+	if wait < 0 || wait > maxRateLimitWait {
+		return 0, false
+	}
+	return wait, true
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"pm/models"
+	"time"
+)
+
+// perPage is the page size of the paginated listings below.
+const perPage = 100
+
+// ListUserRepos returns every repository of the user that passes the repo
+// filter, following pagination. Profile repos are skipped as in GetUserRepos.
+func ListUserRepos(token string) ([]models.GithubRepo, error) {
+	var repos []models.GithubRepo
+	for page := 1; ; page++ {
+		var batch []models.GithubRepo
+		url := fmt.Sprintf("%s/user/repos?per_page=%d&page=%d", githubAPI, perPage, page)
+		if err := sendJSON(token, "GET", url, nil, &batch, "repositories"); err != nil {
+			return nil, err
+		}
+		for _, repo := range batch {
+			if repo.Owner.Login != repo.Name && RepoAllowed(repo.FullName) {
+				repos = append(repos, repo)
+			}
+		}
+		if len(batch) < perPage {
+			return repos, nil
+		}
+	}
+}
+
+// ListMergedPRsUpdatedSince returns the merged pull requests of a repository
+// updated at or after since, with their line and file counts. It pages through
+// closed pull requests newest-updated first and stops at the first older one.
+func ListMergedPRsUpdatedSince(token, owner, repo string, since time.Time) ([]models.PullRequest, error) {
+	var merged []models.PullRequest
+	for page := 1; ; page++ {
+		var batch []models.PullRequest
+		url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=closed&sort=updated&direction=desc&per_page=%d&page=%d", githubAPI, owner, repo, perPage, page)
+		if err := sendJSON(token, "GET", url, nil, &batch, "pull requests of "+owner+"/"+repo); err != nil {
+			return nil, err
+		}
+		for _, pr := range batch {
+			updatedAt, err := time.Parse(time.RFC3339, pr.UpdatedAt)
+			if err == nil && updatedAt.Before(since) {
+				return merged, nil
+			}
+			if pr.MergedAt == "" {
+				continue
+			}
+			var detailed models.PullRequest
+			url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", githubAPI, owner, repo, pr.Number)
+			if err := sendJSON(token, "GET", url, nil, &detailed, fmt.Sprintf("pull request %s/%s#%d", owner, repo, pr.Number)); err != nil {
+				return nil, err
+			}
+			merged = append(merged, detailed)
+		}
+		if len(batch) < perPage {
+			return merged, nil
+		}
+	}
+}
+
+// ListCommitsSince returns the commits username authored in a repository
+// since the given time, following pagination. An empty repository has none.
+func ListCommitsSince(token, owner, repo, username string, since time.Time) ([]models.Commit, error) {
+	var commits []models.Commit
+	for page := 1; ; page++ {
+		var batch []models.Commit
+		url := fmt.Sprintf("%s/repos/%s/%s/commits?author=%s&since=%s&per_page=%d&page=%d", githubAPI, owner, repo, username, since.UTC().Format(time.RFC3339), perPage, page)
+		err := sendJSON(token, "GET", url, nil, &batch, "commits of "+owner+"/"+repo)
+		if errors.Is(err, ErrConflict) {
+			// GitHub answers 409 Conflict for an empty repository.
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, batch...)
+		if len(batch) < perPage {
+			return commits, nil
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"pm/models"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultProfileName = "default"
+
+// Config is the on-disk configuration file: a set of named profiles.
+type Config struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+type Profile struct {
+	Name        string            `yaml:"-"`
+	GitHub      GitHubConfig      `yaml:"github"`
+	ProfileRepo ProfileRepoConfig `yaml:"profile_repo"`
+	Report      ReportConfig      `yaml:"report"`
+	Repos       RepoFilter        `yaml:"repos"`
+	Timezone    string            `yaml:"timezone"`
+	Badges      BadgeConfig       `yaml:"badges"`
+	StatsCard   StatsCardConfig   `yaml:"stats_card"`
+	ReadmeStats ReadmeStatsConfig `yaml:"readme_stats"`
+	Store       StoreConfig       `yaml:"store"`
+}
+
+type GitHubConfig struct {
+	Host         string `yaml:"host"`
+	TokenEnv     string `yaml:"token_env"`
+	TokenFile    string `yaml:"token_file"`
+	TokenCommand string `yaml:"token_command"`
+}
+
+type ProfileRepoConfig struct {
+	Path   string `yaml:"path"`
+	Remote string `yaml:"remote"`
+	Branch string `yaml:"branch"`
+	Readme string `yaml:"readme"`
+	// Writer is "git" to commit in the local clone at Path, or "api" to write
+	// Repo through the GitHub API without a clone. "contents" is a deprecated
+	// alias of "api".
+	Writer string `yaml:"writer"`
+	// Mode is "push" to commit to Branch, or "pull_request" to commit to a
+	// pm/badges-<date> branch and open a pull request into Branch.
+	Mode          string                `yaml:"mode"`
+	Repo          string                `yaml:"repo"`
+	CommitMessage string                `yaml:"commit_message"`
+	Author        models.CommitIdentity `yaml:"author"`
+	// Sign signs git commits, with SigningKey or git's user.signingkey.
+	Sign       bool   `yaml:"sign"`
+	SigningKey string `yaml:"signing_key"`
+}
+
+type ReportConfig struct {
+	OutputDir string `yaml:"output_dir"`
+	Template  string `yaml:"template"`
+}
+
+type RepoFilter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+type BadgeConfig struct {
+	Enabled  *bool              `yaml:"enabled"`
+	Renderer string             `yaml:"renderer"`
+	Style    string             `yaml:"style"`
+	Ledger   string             `yaml:"ledger"`
+	Rules    []models.BadgeRule `yaml:"rules"`
+}
+
+type StatsCardConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Path      string   `yaml:"path"`
+	Themes    []string `yaml:"themes"`
+	Languages int      `yaml:"languages"`
+}
+
+type ReadmeStatsConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Template string `yaml:"template"`
+	Range    string `yaml:"range"`
+}
+
+// StoreConfig enables the local metrics store. Reports read from it when it
+// covers their period and was collected within MaxAge.
+type StoreConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Path    string        `yaml:"path"`
+	MaxAge  time.Duration `yaml:"max_age"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/pm/config.yaml, falling back to ~/.config.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "pm", "config.yaml")
+}
+
+// DataDir returns $XDG_DATA_HOME/pm, falling back to ~/.local/share/pm.
+func DataDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "pm")
+}
+
+// Load reads the config file and resolves the requested profile, applying
+// defaults and PM_* environment overrides. An empty configPath uses PM_CONFIG
+// or DefaultPath, and a missing default file yields an all-defaults profile.
+// An empty profileName uses PM_PROFILE or the file's default_profile.
+func Load(configPath, profileName string) (Profile, error) {
+	explicit := configPath != ""
+	if !explicit {
+		if configPath = os.Getenv("PM_CONFIG"); configPath != "" {
+			explicit = true
+		} else {
+			configPath = DefaultPath()
+		}
+	}
+
+	var cfg Config
+	content, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return Profile{}, fmt.Errorf("failed to parse config %s: %w", configPath, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+	default:
+		return Profile{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if profileName == "" {
+		profileName = os.Getenv("PM_PROFILE")
+	}
+	if profileName == "" {
+		profileName = cfg.DefaultProfile
+	}
+	if profileName == "" {
+		profileName = DefaultProfileName
+	}
+
+	profile, ok := cfg.Profiles[profileName]
+	if !ok && (len(cfg.Profiles) > 0 || profileName != DefaultProfileName) {
+		return Profile{}, fmt.Errorf("profile %q not found in %s", profileName, configPath)
+	}
+	profile.Name = profileName
+
+	profile.applyDefaults()
+	profile.applyEnv()
+	profile.applyAliases()
+	return profile, nil
+}
+
+func (p *Profile) applyDefaults() {
+	if p.GitHub.Host == "" {
+		p.GitHub.Host = "github.com"
+	}
+	if p.GitHub.TokenEnv == "" {
+		p.GitHub.TokenEnv = "GITHUB_TOKEN"
+	}
+	if p.ProfileRepo.Remote == "" {
+		p.ProfileRepo.Remote = "origin"
+	}
+	if p.ProfileRepo.Branch == "" {
+		p.ProfileRepo.Branch = "main"
+	}
+	if p.ProfileRepo.Readme == "" {
+		p.ProfileRepo.Readme = "README.md"
+	}
+	if p.ProfileRepo.Writer == "" {
+		p.ProfileRepo.Writer = "git"
+	}
+	if p.ProfileRepo.Mode == "" {
+		p.ProfileRepo.Mode = "push"
+	}
+	if p.ProfileRepo.CommitMessage == "" {
+		p.ProfileRepo.CommitMessage = "🤖 Update badges in README"
+	}
+	if p.Report.OutputDir == "" {
+		p.Report.OutputDir = "reports"
+	}
+	if p.Badges.Renderer == "" {
+		p.Badges.Renderer = "svg"
+	}
+	if p.Badges.Style == "" {
+		p.Badges.Style = "flat"
+	}
+	if p.Badges.Enabled == nil {
+		enabled := true
+		p.Badges.Enabled = &enabled
+	}
+	if p.StatsCard.Path == "" {
+		p.StatsCard.Path = "pm-stats.svg"
+	}
+	if len(p.StatsCard.Themes) == 0 {
+		p.StatsCard.Themes = []string{"light", "dark"}
+	}
+	if p.StatsCard.Languages == 0 {
+		p.StatsCard.Languages = 5
+	}
+	if p.ReadmeStats.Range == "" {
+		p.ReadmeStats.Range = "weekly"
+	}
+	if p.Store.Path == "" {
+		p.Store.Path = filepath.Join(DataDir(), p.Name+".db")
+	}
+	if p.Store.MaxAge == 0 {
+		p.Store.MaxAge = time.Hour
+	}
+	p.ProfileRepo.Path = expandHome(p.ProfileRepo.Path)
+	p.Report.OutputDir = expandHome(p.Report.OutputDir)
+	p.Report.Template = expandHome(p.Report.Template)
+	p.GitHub.TokenFile = expandHome(p.GitHub.TokenFile)
+	p.Badges.Ledger = expandHome(p.Badges.Ledger)
+	p.ReadmeStats.Template = expandHome(p.ReadmeStats.Template)
+	p.Store.Path = expandHome(p.Store.Path)
+}
+
+// applyAliases rewrites deprecated values to their current names.
+func (p *Profile) applyAliases() {
+	if p.ProfileRepo.Writer == "contents" {
+		log.Printf("⚠️ profile_repo.writer: contents is deprecated, use writer: api")
+		p.ProfileRepo.Writer = "api"
+	}
+}
+
+func (p *Profile) applyEnv() {
+	overrides := map[string]*string{
+		"PM_GITHUB_HOST":    &p.GitHub.Host,
+		"PM_PROFILE_REPO":   &p.ProfileRepo.Path,
+		"PM_PROFILE_BRANCH": &p.ProfileRepo.Branch,
+		"PM_PROFILE_WRITER": &p.ProfileRepo.Writer,
+		"PM_PROFILE_GITHUB": &p.ProfileRepo.Repo,
+		"PM_REPORT_DIR":     &p.Report.OutputDir,
+		"PM_STORE":          &p.Store.Path,
+		"PM_TEMPLATE":       &p.Report.Template,
+		"PM_TIMEZONE":       &p.Timezone,
+	}
+	for env, field := range overrides {
+		if v := os.Getenv(env); v != "" {
+			*field = expandHome(v)
+		}
+	}
+}
+
+// Validate reports every problem with the profile at once.
+func (p Profile) Validate() error {
+	var errs []error
+	if strings.Contains(p.GitHub.Host, "/") {
+		errs = append(errs, fmt.Errorf("github.host %q must be a host name, not a URL", p.GitHub.Host))
+	}
+	if p.GitHub.TokenFile != "" && p.GitHub.TokenCommand != "" {
+		errs = append(errs, errors.New("github.token_file and github.token_command are mutually exclusive"))
+	}
+	if p.ProfileRepo.Path != "" {
+		if info, err := os.Stat(p.ProfileRepo.Path); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("profile_repo.path %q is not a directory", p.ProfileRepo.Path))
+		}
+	}
+	if p.ProfileRepo.Writer != "git" && p.ProfileRepo.Writer != "api" {
+		errs = append(errs, fmt.Errorf("profile_repo.writer %q: expected git or api", p.ProfileRepo.Writer))
+	}
+	if p.ProfileRepo.Mode != "push" && p.ProfileRepo.Mode != "pull_request" {
+		errs = append(errs, fmt.Errorf("profile_repo.mode %q: expected push or pull_request", p.ProfileRepo.Mode))
+	}
+	if p.ProfileRepo.Repo != "" && strings.Count(p.ProfileRepo.Repo, "/") != 1 {
+		errs = append(errs, fmt.Errorf("profile_repo.repo %q: expected owner/name", p.ProfileRepo.Repo))
+	}
+	if p.ProfileRepo.Sign && p.UsesAPIWriter() {
+		errs = append(errs, errors.New("profile_repo.sign only applies to writer: git; GitHub signs API commits itself"))
+	}
+	if (p.ProfileRepo.Author.Name == "") != (p.ProfileRepo.Author.Email == "") {
+		errs = append(errs, errors.New("profile_repo.author needs both name and email"))
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("timezone %q: %w", p.Timezone, err))
+		}
+	}
+	if p.Badges.Renderer != "svg" && p.Badges.Renderer != "shields" {
+		errs = append(errs, fmt.Errorf("badges.renderer %q: expected svg or shields", p.Badges.Renderer))
+	}
+	if p.Badges.Style != "flat" && p.Badges.Style != "flat-square" {
+		errs = append(errs, fmt.Errorf("badges.style %q: expected flat or flat-square", p.Badges.Style))
+	}
+	for _, theme := range p.StatsCard.Themes {
+		if theme != "light" && theme != "dark" {
+			errs = append(errs, fmt.Errorf("stats_card.themes: unknown theme %q: expected light or dark", theme))
+		}
+	}
+	if p.StatsCard.Enabled && !p.HasProfileRepo() {
+		errs = append(errs, errors.New("stats_card.enabled requires profile_repo.path or profile_repo.writer: api"))
+	}
+	if p.ReadmeStats.Enabled && !p.HasProfileRepo() {
+		errs = append(errs, errors.New("readme_stats.enabled requires profile_repo.path or profile_repo.writer: api"))
+	}
+	if p.Store.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("store.max_age %s must not be negative", p.Store.MaxAge))
+	}
+	for _, pattern := range append(append([]string{}, p.Repos.Include...), p.Repos.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("repos pattern %q: %w", pattern, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid profile %q: %w", p.Name, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Token resolves the GitHub token from token_file, token_command or token_env.
+func (p Profile) Token() (string, error) {
+	switch {
+	case p.GitHub.TokenFile != "":
+		content, err := os.ReadFile(p.GitHub.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	case p.GitHub.TokenCommand != "":
+		out, err := exec.Command("sh", "-c", p.GitHub.TokenCommand).Output()
+		if err != nil {
+			return "", fmt.Errorf("token command failed: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	token := os.Getenv(p.GitHub.TokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("set %s environment variable", p.GitHub.TokenEnv)
+	}
+	return token, nil
+}
+
+// UsesAPIWriter reports whether the profile repo is written through the
+// GitHub API. Its paths are then relative to the repository root.
+func (p Profile) UsesAPIWriter() bool {
+	return p.ProfileRepo.Writer == "api"
+}
+
+// HasProfileRepo reports whether a profile repo is configured, either as a
+// local clone or through the GitHub API.
+func (p Profile) HasProfileRepo() bool {
+	return p.ProfileRepo.Path != "" || p.UsesAPIWriter()
+}
+
+func (p Profile) ReadmePath() string {
+	if !p.HasProfileRepo() {
+		return ""
+	}
+	return filepath.Join(p.ProfileRepo.Path, p.ProfileRepo.Readme)
+}
+
+// LedgerPath is where earned badges are recorded: badges.ledger (relative to
+// the profile repo unless absolute), .pm/badges.json in the profile repo, or
+// the pm data dir when no profile repo is configured.
+func (p Profile) LedgerPath() string {
+	switch {
+	case filepath.IsAbs(p.Badges.Ledger):
+		return p.Badges.Ledger
+	case !p.HasProfileRepo():
+		return filepath.Join(DataDir(), "badges.json")
+	case p.Badges.Ledger != "":
+		return filepath.Join(p.ProfileRepo.Path, p.Badges.Ledger)
+	}
+	return filepath.Join(p.ProfileRepo.Path, ".pm", "badges.json")
+}
+
+func (p Profile) BadgesEnabled() bool {
+	return p.Badges.Enabled != nil && *p.Badges.Enabled && p.HasProfileRepo()
+}
+
+// StatsCardFiles maps each stats card theme to its file in the profile repo:
+// stats_card.path with the theme name before the extension.
+func (p Profile) StatsCardFiles() map[string]string {
+	ext := filepath.Ext(p.StatsCard.Path)
+	base := strings.TrimSuffix(p.StatsCard.Path, ext)
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(p.ProfileRepo.Path, base)
+	}
+	files := map[string]string{}
+	for _, theme := range p.StatsCard.Themes {
+		files[theme] = base + "-" + theme + ext
+	}
+	return files
+}
+
+// Now returns the current time in the profile's timezone.
+func (p Profile) Now() time.Time {
+	return time.Now().In(p.Location())
+}
+
+func (p Profile) Location() *time.Location {
+	if p.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
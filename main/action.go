@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	gitService "pm/service"
+	"pm/utils"
+	"strconv"
+	"strings"
+)
+
+// actionInput returns a GitHub Action input, which the runner passes as
+// INPUT_<NAME>.
+func actionInput(name string) string {
+	return strings.TrimSpace(os.Getenv("INPUT_" + strings.ToUpper(name)))
+}
+
+// runAction runs pm as a GitHub Action step: it appends the detailed report in
+// Markdown to the job summary, writes the report data as JSON and sets step
+// outputs. Inputs come from INPUT_* variables rather than flags, so it can be
+// tried locally by exporting them along with GITHUB_STEP_SUMMARY and
+// GITHUB_OUTPUT.
+func runAction(args []string) error {
+	fs := newFlagSet("action", "action (inputs are read from INPUT_* environment variables)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	o := options{
+		configPath: actionInput("config"),
+		profile:    actionInput("profile"),
+		repos:      actionInput("repos"),
+		since:      actionInput("since"),
+		rangeName:  actionInput("range"),
+	}
+	if o.rangeName == "" {
+		o.rangeName = "weekly"
+	}
+	profile, err := o.loadProfile()
+	if err != nil {
+		return err
+	}
+	since, err := o.sinceTime(profile.Location())
+	if err != nil {
+		return err
+	}
+	token := actionInput("token")
+	if token == "" {
+		if token, err = profile.Token(); err != nil {
+			return fmt.Errorf("%w, or pass the token input", err)
+		}
+	}
+
+	source, closeSource, err := reportSource(profile, token)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	data, err := source.Get(since)
+	if err != nil {
+		return err
+	}
+	tmpl, err := gitService.LoadTemplate(actionInput("template"), "markdown")
+	if err != nil {
+		return err
+	}
+	report, err := gitService.RenderTemplate(tmpl, gitService.NewTemplateData(strings.Title(o.periodName())+" report", data))
+	if err != nil {
+		return err
+	}
+	if os.Getenv("GITHUB_STEP_SUMMARY") == "" {
+		fmt.Print(report)
+	} else if err := utils.AppendStepSummary(report); err != nil {
+		return err
+	}
+
+	jsonPath := actionInput("json_path")
+	if jsonPath == "" {
+		jsonPath = filepath.Join(os.Getenv("RUNNER_TEMP"), "pm-report.json")
+	}
+	content, err := gitService.ExportJSON(data)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(jsonPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", jsonPath, err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Report data saved to %s\n", jsonPath)
+
+	metrics := gitService.ComputeMetrics(data)
+	return utils.SetOutputs(map[string]string{
+		"json":                  jsonPath,
+		"repositories":          strconv.Itoa(metrics.Repositories),
+		"prs_merged":            strconv.Itoa(metrics.PRsMerged),
+		"commits":               strconv.Itoa(metrics.Commits),
+		"additions":             strconv.Itoa(metrics.Additions),
+		"deletions":             strconv.Itoa(metrics.Deletions),
+		"reviews":               strconv.Itoa(metrics.Reviews),
+		"issues_fixed":          strconv.Itoa(metrics.IssuesFixed),
+		"median_hours_to_merge": strconv.FormatFloat(metrics.MedianTimeToMerge.Hours(), 'f', 2, 64),
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	githubclient "pm/client"
+	gitService "pm/service"
+	"pm/store"
+	"time"
+)
+
+func runBackfill(args []string) error {
+	var o options
+	fs := newFlagSet("backfill", "backfill --from YYYY-MM-DD")
+	o.addProfileFlags(fs)
+	fromFlag := fs.String("from", "", "first day to backfill (YYYY-MM-DD), rounded back to its Monday")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *fromFlag == "" {
+		return usagef("--from is required")
+	}
+
+	profile, err := o.loadProfile()
+	if err != nil {
+		return err
+	}
+	from, err := time.ParseInLocation("2006-01-02", *fromFlag, profile.Location())
+	if err != nil {
+		return usagef("invalid --from date %q: expected YYYY-MM-DD", *fromFlag)
+	}
+	if !from.Before(gitService.WeekStart(profile.Now())) {
+		return usagef("--from %s is in the current week: there is nothing to backfill", *fromFlag)
+	}
+	token, err := profile.Token()
+	if err != nil {
+		return err
+	}
+	if !profile.Store.Enabled {
+		fmt.Fprintln(os.Stderr, "ℹ️ The metrics store is disabled, so reports will not read what is backfilled: set store.enabled in the config.")
+	}
+	username, err := githubclient.GetGitHubUsername(token)
+	if err != nil {
+		return fmt.Errorf("could not retrieve GitHub username: %w", err)
+	}
+
+	st, err := store.Open(profile.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	snapshots, err := gitService.Backfill(st, token, username, from, printProgress)
+	if err != nil {
+		return fmt.Errorf("%w\nProgress is saved: run `pm backfill` again to resume", err)
+	}
+	for _, snapshot := range snapshots {
+		fmt.Printf("📦 Week of %s: %d PRs merged, %d commits, %d reviews\n",
+			snapshot.Start.Format("2006-01-02"), snapshot.Metrics.PRsMerged, snapshot.Metrics.Commits, snapshot.Metrics.Reviews)
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("✅ Synced, no complete week to snapshot yet")
+		return nil
+	}
+	fmt.Printf("✅ The store has weekly snapshots from %s to %s\n",
+		snapshots[0].Start.Format("2006-01-02"), snapshots[len(snapshots)-1].End.Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"pm/config"
+	"pm/models"
+	gitService "pm/service"
+	"pm/svg"
+	"pm/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runBadges(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "-help", "--help", "help":
+			fmt.Fprintln(os.Stderr, "Usage: pm badges check|sync [flags]\n\nRun `pm badges <action> -h` for action flags.")
+			return flag.ErrHelp
+		}
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usagef("usage: pm badges check|sync [flags]")
+	}
+
+	var o options
+	action := args[0]
+	fs := newFlagSet("badges "+action, "badges check|sync [--range yearly | --since YYYY-MM-DD] [--dry-run] [--pull-request]")
+	o.addProfileFlags(fs)
+	o.addRangeFlags(fs, "yearly")
+	pullRequest := false
+	if action == "sync" {
+		fs.BoolVar(&o.dryRun, "dry-run", false, "show what would change without writing or pushing")
+		fs.BoolVar(&pullRequest, "pull-request", false, "commit to a pm/badges-<date> branch and open a pull request instead of pushing")
+	}
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "check", "sync":
+	default:
+		return usagef("unknown badges action %q: expected check or sync", action)
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+	if pullRequest {
+		profile.ProfileRepo.Mode = "pull_request"
+	}
+	since, err := o.sinceTime(profile.Location())
+	if err != nil {
+		return err
+	}
+	state, err := evaluateBadges(profile, token, since)
+	if err != nil {
+		return err
+	}
+	gitService.DisplayExistingBadges(state.existing, state.results, "📛 Earned Badges:")
+	fmt.Print(gitService.FormatReadmeScan(state.scan))
+
+	fmt.Println("\n🏅 Newly Unlocked Badges:")
+	if len(state.newBadges) == 0 {
+		fmt.Println("No new badges to add.")
+	}
+	for _, badge := range state.newBadges {
+		fmt.Println("-", badge)
+	}
+	fmt.Print(formatExpired(state.expired))
+	if action == "check" {
+		return nil
+	}
+
+	update, err := planProfileUpdate(profile, state)
+	if err != nil {
+		return err
+	}
+	var result applyResult
+	switch {
+	case update.empty():
+		fmt.Println("Profile repo is already up to date.")
+	case o.dryRun:
+		fmt.Println("\n🔎 Dry run, nothing written:")
+		fmt.Print(update.preview())
+	default:
+		if result, err = update.apply(); err != nil {
+			return err
+		}
+	}
+	return reportBadgeSync(state, result)
+}
+
+// reportBadgeSync writes the job summary and step outputs when running in
+// GitHub Actions, e.g. from a repository_dispatch workflow.
+func reportBadgeSync(state badgeState, result applyResult) error {
+	summary := gitService.BadgeSummaryMarkdown(state.existing, state.newBadges, state.expired, state.results)
+	if len(result.files) > 0 {
+		summary += "\n**Updated files:** " + strings.Join(result.files, ", ") + "\n"
+	}
+	if result.pullRequest != "" {
+		summary += "\n**Pull request:** " + result.pullRequest + "\n"
+	}
+	if err := utils.AppendStepSummary(summary); err != nil {
+		return err
+	}
+	return utils.SetOutputs(map[string]string{
+		"new_badges":     strconv.Itoa(len(state.newBadges)),
+		"badges":         strings.Join(state.newBadges, "\n"),
+		"expired_badges": strings.Join(state.expired, "\n"),
+		"changed":        strconv.FormatBool(len(result.files) > 0),
+		"committed":      strconv.FormatBool(result.committed),
+		"pull_request":   result.pullRequest,
+	})
+}
+
+// formatExpired lists badges that expired in this run, or returns "".
+func formatExpired(expired []string) string {
+	if len(expired) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n🗑 Expired Badges (removed on sync):\n")
+	for _, badge := range expired {
+		fmt.Fprintln(&b, "-", badge)
+	}
+	return b.String()
+}
+
+// badgeState is the outcome of evaluating badge rules against the ledger.
+type badgeState struct {
+	existing  []string
+	results   []gitService.BadgeResult
+	newBadges []string
+	expired   []string
+	ledger    models.BadgeLedger
+	scan      gitService.ReadmeScan
+	files     profileFiles
+	readme    string
+	token     string
+	// cache holds the data fetched for the badges, for reuse by the stats
+	// card and README stats section.
+	cache *gitService.ReportDataCache
+	since time.Time
+}
+
+func evaluateBadges(profile config.Profile, token string, since time.Time) (badgeState, error) {
+	state := badgeState{token: token}
+	var err error
+	if state.files, err = openProfileFiles(profile, token); err != nil {
+		return state, err
+	}
+	if state.readme, err = profileReadme(profile, state.files); err != nil {
+		return state, err
+	}
+	ledger, err := state.files.ReadFile(profile.LedgerPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return state, fmt.Errorf("failed to read badge ledger: %w", err)
+	}
+	if state.ledger, err = gitService.ParseLedger(ledger, profile.LedgerPath()); err != nil {
+		return state, err
+	}
+
+	rules := badgeRules(profile)
+	state.existing, state.newBadges = gitService.GetBadgesFromContent(state.readme, rules, &state.ledger, profile.Location())
+	state.cache, state.since = gitService.NewReportDataCache(token), since
+	if state.results, err = gitService.EvaluateBadgeRules(state.cache, rules, since); err != nil {
+		return state, err
+	}
+	state.newBadges, state.expired = gitService.GetNewBadges(state.results, &state.ledger, state.newBadges)
+	if len(state.expired) > 0 {
+		// Expired badges no longer count as earned.
+		state.existing, _ = gitService.GetBadgesFromContent(state.readme, rules, &state.ledger, profile.Location())
+	}
+	state.scan = gitService.ScanReadmeBadges(state.readme, rules, state.ledger)
+	return state, nil
+}
+
+// planProfileUpdate regenerates the README badge block from the ledger and
+// stages the ledger itself, along with the stats card and README stats section
+// when enabled.
+func planProfileUpdate(profile config.Profile, state badgeState) (profileUpdate, error) {
+	update := profileUpdate{
+		profile:     profile,
+		files:       state.files,
+		token:       state.token,
+		description: gitService.BadgePullRequestBody(state.results, state.newBadges, state.expired),
+	}
+
+	rules := badgeRules(profile)
+	renderer := profile.Badges.Renderer
+	readme := gitService.UpdateBadgeBlock(state.readme, rules, gitService.LedgerBadges(state.ledger, rules, renderer))
+	if profile.ReadmeStats.Enabled {
+		period := profile.ReadmeStats.Range
+		since, _ := gitService.PeriodStart(period, profile.Now())
+		data, err := state.cache.Get(since)
+		if err != nil {
+			return update, err
+		}
+		section, err := gitService.RenderStatsSection(data, strings.Title(period)+" activity", profile.ReadmeStats.Template)
+		if err != nil {
+			return update, err
+		}
+		readme = utils.SetStatsBlock(readme, section)
+	}
+	if err := update.add(profile.ReadmePath(), readme); err != nil {
+		return update, err
+	}
+
+	if renderer == gitService.RendererSVG {
+		readmeDir := filepath.Dir(profile.ReadmePath())
+		files := gitService.LedgerBadgeSVGs(state.ledger, rules, svg.Style(profile.Badges.Style))
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			if err := update.add(filepath.Join(readmeDir, name), files[name]); err != nil {
+				return update, err
+			}
+		}
+	}
+	if profile.StatsCard.Enabled {
+		data, err := state.cache.Get(state.since)
+		if err != nil {
+			return update, err
+		}
+		cards := profile.StatsCardFiles()
+		for _, theme := range slices.Sorted(maps.Keys(cards)) {
+			card, err := gitService.BuildStatsCard(data, theme, profile.StatsCard.Languages)
+			if err != nil {
+				return update, err
+			}
+			if err := update.add(cards[theme], card); err != nil {
+				return update, err
+			}
+		}
+	}
+	if err := update.add(profile.LedgerPath(), gitService.MarshalLedger(state.ledger)); err != nil {
+		return update, err
+	}
+
+	// Commits a failed rebase or push left behind are pushed even when the
+	// files on disk are already up to date.
+	if !profile.UsesAPIWriter() && !update.pullRequest() {
+		unpushed, err := profileGitRepo(profile).Unpushed()
+		if err != nil {
+			return update, fmt.Errorf("cannot check the profile repo for unpushed commits: %w", err)
+		}
+		update.unpushed = unpushed
+	}
+	return update, nil
+}
+
+// profileReadme reads the profile README.
+func profileReadme(profile config.Profile, files profileFiles) (string, error) {
+	readmePath := profile.ReadmePath()
+	if readmePath == "" {
+		return "", fmt.Errorf("no profile repo configured: set profile_repo.path in %s or pass --profile-repo", config.DefaultPath())
+	}
+	if !profile.BadgesEnabled() {
+		return "", fmt.Errorf("badges are disabled in profile %q", profile.Name)
+	}
+	content, err := files.ReadFile(readmePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read profile README: %w", err)
+	}
+	return string(content), nil
+}
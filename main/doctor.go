@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	gitClient "pm/client"
+	"pm/config"
+	"pm/store"
+)
+
+type check struct {
+	name string
+	run  func() (string, error)
+}
+
+func runDoctor(args []string) error {
+	var o options
+	fs := newFlagSet("doctor", "doctor [--config path] [--profile name]")
+	o.addProfileFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var profile config.Profile
+	var token string
+	loaded := false
+	checks := []check{
+		{"config", func() (string, error) {
+			var err error
+			profile, err = o.loadProfile()
+			if err != nil {
+				return "", err
+			}
+			loaded = true
+			return fmt.Sprintf("profile %q from %s", profile.Name, configSource(o)), nil
+		}},
+		{"token", func() (string, error) {
+			if !loaded {
+				return "", errors.New("skipped: no config")
+			}
+			var err error
+			token, err = profile.Token()
+			if err != nil {
+				return "", err
+			}
+			return "found", nil
+		}},
+		{"github", func() (string, error) {
+			if token == "" {
+				return "", errors.New("skipped: no token")
+			}
+			username, err := gitClient.GetGitHubUsername(token)
+			if err != nil {
+				return "", err
+			}
+			if username == "" {
+				return "", errors.New("token was rejected")
+			}
+			return fmt.Sprintf("authenticated as %s on %s", username, profile.GitHub.Host), nil
+		}},
+		{"git", func() (string, error) {
+			path, err := exec.LookPath("git")
+			if err != nil {
+				return "", err
+			}
+			return path, nil
+		}},
+		{"profile repo", func() (string, error) {
+			if profile.UsesAPIWriter() {
+				if token == "" {
+					return "", errors.New("skipped: no token")
+				}
+				files, err := openProfileFiles(profile, token)
+				if err != nil {
+					return "", err
+				}
+				if _, err := files.ReadFile(profile.ReadmePath()); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s in %s via the GitHub API", profile.ProfileRepo.Readme, files.(apiFiles).repo.FullName()), nil
+			}
+			if profile.ProfileRepo.Path == "" {
+				return "not configured, badge commands are disabled", nil
+			}
+			if err := profileGitRepo(profile).Check(nil); err != nil {
+				return "", err
+			}
+			if _, err := os.Stat(profile.ReadmePath()); err != nil {
+				return "", err
+			}
+			return profile.ReadmePath(), nil
+		}},
+		{"metrics store", func() (string, error) {
+			if !profile.Store.Enabled {
+				return "disabled", nil
+			}
+			st, err := store.Open(profile.Store.Path)
+			if err != nil {
+				return "", err
+			}
+			defer st.Close()
+			coverage, err := st.Coverage()
+			if err != nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("%s (schema v%d)", st.Path(), store.SchemaVersion)
+			if !coverage.FetchedAt.IsZero() {
+				detail += fmt.Sprintf(", covers %s since %s, collected %s", coverage.Username, coverage.Since.Format("2006-01-02"), coverage.FetchedAt.Format("2006-01-02 15:04"))
+			}
+			return detail, nil
+		}},
+	}
+
+	failed := 0
+	for _, c := range checks {
+		detail, err := c.run()
+		if err != nil {
+			failed++
+			fmt.Printf("❌ %-13s %v\n", c.name, err)
+			continue
+		}
+		fmt.Printf("✅ %-13s %s\n", c.name, detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func configSource(o options) string {
+	switch {
+	case o.configPath != "":
+		return o.configPath
+	case os.Getenv("PM_CONFIG") != "":
+		return os.Getenv("PM_CONFIG")
+	}
+	if _, err := os.Stat(config.DefaultPath()); err != nil {
+		return "defaults (no config file)"
+	}
+	return config.DefaultPath()
+}
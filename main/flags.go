@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	gitClient "pm/client"
+	"pm/config"
+	"pm/models"
+	gitService "pm/service"
+	"pm/store"
+	"strings"
+	"time"
+)
+
+// usageError marks errors caused by bad command-line input.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// options holds every flag a subcommand may register.
+type options struct {
+	configPath  string
+	profile     string
+	reportDir   string
+	profileRepo string
+	repos       string
+
+	since     string
+	rangeName string
+	compare   bool
+
+	format   string
+	output   string
+	template string
+	dryRun   bool
+}
+
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("pm "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: pm %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return usageError{err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+func (o *options) addProfileFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", "", "config file (default $XDG_CONFIG_HOME/pm/config.yaml)")
+	fs.StringVar(&o.profile, "profile", "", "config profile to use")
+	fs.StringVar(&o.reportDir, "report-dir", "", "directory reports are written to")
+	fs.StringVar(&o.profileRepo, "profile-repo", "", "local clone of the GitHub profile repo")
+	fs.StringVar(&o.repos, "repos", "", "comma-separated owner/name patterns to include, overriding the config")
+}
+
+func (o *options) addRangeFlags(fs *flag.FlagSet, defaultRange string) {
+	fs.StringVar(&o.since, "since", "", "start date (YYYY-MM-DD); overrides --range")
+	fs.StringVar(&o.rangeName, "range", defaultRange, "period: "+strings.Join(gitService.Periods, ", "))
+}
+
+func (o *options) addCompareFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&o.compare, "compare", false, "compare with the previous period of the same length")
+}
+
+func (o *options) addOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "text", "output format: text or csv")
+	fs.StringVar(&o.output, "output", "", "write to this file (text) or directory (csv) instead of the default")
+	fs.StringVar(&o.template, "template", "", "path to a text/template file used to render the report")
+}
+
+// sinceTime resolves --since or --range in loc, the profile's timezone.
+func (o options) sinceTime(loc *time.Location) (time.Time, error) {
+	if o.since != "" {
+		since, err := time.ParseInLocation("2006-01-02", o.since, loc)
+		if err != nil {
+			return time.Time{}, usagef("invalid --since date %q: expected YYYY-MM-DD", o.since)
+		}
+		return since, nil
+	}
+	since, err := gitService.PeriodStart(o.rangeName, time.Now().In(loc))
+	if err != nil {
+		return time.Time{}, usageError{err.Error()}
+	}
+	return since, nil
+}
+
+// periodName is the label used in report titles.
+func (o options) periodName() string {
+	if o.since != "" {
+		return "since " + o.since
+	}
+	return o.rangeName
+}
+
+// loadProfile resolves the config profile, applies flag overrides on top of
+// file and env settings, and configures the client for it.
+func (o options) loadProfile() (config.Profile, error) {
+	profile, err := config.Load(o.configPath, o.profile)
+	if err != nil {
+		return profile, err
+	}
+	if o.reportDir != "" {
+		profile.Report.OutputDir = o.reportDir
+	}
+	if o.profileRepo != "" {
+		profile.ProfileRepo.Path = o.profileRepo
+	}
+	if o.template != "" {
+		profile.Report.Template = o.template
+	}
+	if o.repos != "" {
+		profile.Repos.Include = strings.Split(o.repos, ",")
+	}
+	if err := profile.Validate(); err != nil {
+		return profile, err
+	}
+	if err := gitService.ValidateBadgeRules(profile.Badges.Rules); err != nil {
+		return profile, fmt.Errorf("invalid badge rules in profile %q: %w", profile.Name, err)
+	}
+	if _, err := gitService.PeriodStart(profile.ReadmeStats.Range, time.Now()); err != nil {
+		return profile, fmt.Errorf("invalid readme_stats.range in profile %q: %w", profile.Name, err)
+	}
+
+	gitClient.SetHost(profile.GitHub.Host)
+	gitClient.SetRepoFilter(profile.Repos.Include, profile.Repos.Exclude)
+	return profile, nil
+}
+
+// badgeRules returns the profile's badge rules, or the built-in ones.
+func badgeRules(profile config.Profile) []models.BadgeRule {
+	if len(profile.Badges.Rules) > 0 {
+		return profile.Badges.Rules
+	}
+	return gitService.DefaultBadgeRules
+}
+
+// setup loads the profile and resolves its token.
+func (o options) setup() (config.Profile, string, error) {
+	profile, err := o.loadProfile()
+	if err != nil {
+		return profile, "", err
+	}
+	token, err := profile.Token()
+	return profile, token, err
+}
+
+// reportSource returns where report data comes from: the metrics store when
+// enabled, or GitHub directly. close releases the store.
+func reportSource(profile config.Profile, token string) (source gitService.ReportSource, close func(), err error) {
+	if !profile.Store.Enabled {
+		return gitService.NewReportDataCache(token), func() {}, nil
+	}
+	st, err := store.Open(profile.Store.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gitService.NewStoreSource(st, token, profile.Store.MaxAge), func() { st.Close() }, nil
+}
+
+func writeOutput(path, content string) error {
+	if path == "" || path == "-" {
+		fmt.Print(content)
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Report saved to %s\n", path)
+	return nil
+}
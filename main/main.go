@@ -1,135 +1,95 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"os"
-	gitClient "pm/client"
-	gitService "pm/service"
-	"pm/tui"
-	"pm/utils"
-	"strings"
 	"time"
 )
 
-func main() {
-	if len(os.Args) > 1 && os.Args[1] == "tui" {
-		token := os.Getenv("GITHUB_TOKEN")
-		if token == "" {
-			log.Fatal("Set GITHUB_TOKEN environment variable.")
-		}
-
-		defaultSince := time.Now().AddDate(0, 0, -7)
-		defaultSummary := gitService.BuildSummary(token, defaultSince)
-
-		period, since, ok := tui.RunWithTokenWithSummary(token, defaultSummary)
-
-		if !ok {
-			log.Println("No report generated.")
-			return
-		}
-		if period == "badges" {
-			readmePath := "/Users/yaswood/yassir20191/README.md"
-			content, err := os.ReadFile(readmePath)
-			if err != nil {
-				log.Printf("⚠️ Could not read README: %v", err)
-			} else {
-				existingBadges := []string{}
-				text := string(content)
-
-				if strings.Contains(text, "1st%20PR-achieved") {
-					existingBadges = append(existingBadges, "🎉 1st PR Badge")
-				}
-				if strings.Contains(text, "1st%20Repo-active") {
-					existingBadges = append(existingBadges, "📁 1st Repo Badge")
-				}
-
-				fmt.Println("\n🏅 Developer Badges:")
-				if len(existingBadges) == 0 {
-					fmt.Println("- No badges found.")
-				} else {
-					for _, badge := range existingBadges {
-						fmt.Println("-", badge)
-					}
-				}
-			}
-			return
-		}
-
-		summary := gitService.BuildDetailedReport(token, since)
-		dateStr := time.Now().Format("2006-01-02")
-		os.MkdirAll("reports", os.ModePerm)
-		filename := fmt.Sprintf("reports/report_%s.txt", dateStr)
-		content := fmt.Sprintf("%s report\n\n%s", strings.Title(period), summary)
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
 
-		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
-			log.Fatalf("Failed to write report: %v", err)
-		}
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-		fmt.Printf("✅ Report saved to %s\n", filename)
-		return
+var commands []command
+
+func init() {
+	commands = []command{
+		{"report", "generate a detailed metrics report", runReport},
+		{"summary", "print a short activity summary", runSummary},
+		{"badges", "check or sync profile README badges", runBadges},
+		{"tui", "open the interactive dashboard", runTUI},
+		{"sync", "fetch what changed since the last sync into the metrics store", runSync},
+		{"backfill", "fetch past weeks into the metrics store, with weekly snapshots", runBackfill},
+		{"trends", "chart a metric across stored weekly snapshots", runTrends},
+		{"serve", "serve live badge JSON for shields.io endpoint badges", runServe},
+		{"action", "run as a GitHub Action step, reading INPUT_* variables", runAction},
+		{"doctor", "check configuration, token and profile repo", runDoctor},
 	}
+}
 
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <YYYY-MM-DD>")
-	}
-	sinceDate, err := time.Parse("2006-01-02", os.Args[1])
-	if err != nil {
-		log.Fatalf("Invalid date format: %v", err)
-	}
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		log.Fatal("Set GITHUB_TOKEN environment variable.")
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
 	}
 
-	repos, err := gitClient.GetUserRepos(token, sinceDate)
-	if err != nil {
-		log.Fatal(err)
+	name, rest := args[0], args[1:]
+	switch name {
+	case "-h", "--help", "help":
+		printUsage()
+		return exitOK
 	}
 
-	report := gitService.GenerateFullMetricsReport(token, sinceDate)
-	fmt.Println(report)
+	// Backwards compatibility: `pm 2024-01-01` is `pm report --since 2024-01-01`.
+	if _, err := time.Parse("2006-01-02", name); err == nil {
+		fmt.Fprintln(os.Stderr, "ℹ️ `pm <date>` is deprecated, use `pm report --since <date>`.")
+		name, rest = "report", append([]string{"--since", name}, rest...)
+	}
 
-	// Badge generation: check if user has at least 1 merged PR
-	totalPRs := 0
-	for _, repo := range repos {
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner, repoName := parts[0], parts[1]
-		prs, err := gitClient.GetUserMergedPRs(token, owner, repoName, sinceDate)
-		if err != nil {
-			continue
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return exitCode(cmd.run(rest))
 		}
-		totalPRs += len(prs)
 	}
 
-	// --- Begin badge block ---
-	readmePath := "/Users/yaswood/yassir20191/README.md"
-
-	existingBadges, newBadges, err := gitService.GetBadgesFromReadme(readmePath)
-	gitService.DisplayExistingBadges(existingBadges, "\n📛 Existing Badges in README:")
-	newBadges = gitService.GetNewBadges(token, newBadges, sinceDate, readmePath)
+	fmt.Fprintf(os.Stderr, "pm: unknown command %q\n\n", name)
+	printUsage()
+	return exitUsage
+}
 
-	fmt.Println("\n🏅 Newly Unlocked Badges:")
-	if len(newBadges) == 0 {
-		fmt.Println("No new badges to add.")
-	} else {
-		for _, badge := range newBadges {
-			fmt.Println("- Adding:", badge)
-			err := utils.AddBadgeToReadme(badge, readmePath)
-			if err != nil {
-				log.Println("❌ Failed to update README with badge:", err)
-			} else {
-				fmt.Println("✅ Badge added to README.")
-			}
-		}
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		fmt.Fprintln(os.Stderr, "pm:", err)
+		return exitUsage
+	default:
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return exitError
 	}
-	// --- End badge block ---
+}
 
-	if len(newBadges) > 0 {
-		utils.CommitAndPushProfileReadme()
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: pm <command> [flags]\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nRun `pm <command> -h` for command flags.")
 }
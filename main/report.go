@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	gitService "pm/service"
+	"strings"
+)
+
+func runReport(args []string) error {
+	var o options
+	fs := newFlagSet("report", "report [--range weekly | --since YYYY-MM-DD] [--compare] [--format text|csv] [--output path]")
+	o.addProfileFlags(fs)
+	o.addRangeFlags(fs, "weekly")
+	o.addCompareFlag(fs)
+	o.addOutputFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if o.format != "text" && o.format != "csv" {
+		return usagef("unknown format %q: expected text or csv", o.format)
+	}
+	if o.compare && o.format == "csv" {
+		return usagef("--compare only applies to text reports")
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+	since, err := o.sinceTime(profile.Location())
+	if err != nil {
+		return err
+	}
+	source, closeSource, err := reportSource(profile, token)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	if o.format == "csv" {
+		data, err := source.Get(since)
+		if err != nil {
+			return err
+		}
+		outputDir := profile.Report.OutputDir
+		if o.output != "" {
+			outputDir = o.output
+		}
+		dir, err := gitService.ExportCSV(data, outputDir)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✅ CSV files saved to %s\n", dir)
+		return nil
+	}
+
+	td, err := gitService.LoadTemplateData(source, o.periodName(), since, o.compare)
+	if err != nil {
+		return err
+	}
+	report, err := gitService.RenderReport(td, profile.Report.Template)
+	if err != nil {
+		return err
+	}
+	if profile.Report.Template == "" {
+		report = fmt.Sprintf("%s report\n\n%s", strings.Title(o.periodName()), report)
+	}
+	return writeOutput(o.output, report)
+}
+
+func runSummary(args []string) error {
+	var o options
+	fs := newFlagSet("summary", "summary [--range weekly | --since YYYY-MM-DD] [--compare] [--output path]")
+	o.addProfileFlags(fs)
+	o.addRangeFlags(fs, "weekly")
+	o.addCompareFlag(fs)
+	fs.StringVar(&o.output, "output", "", "write to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+	since, err := o.sinceTime(profile.Location())
+	if err != nil {
+		return err
+	}
+	source, closeSource, err := reportSource(profile, token)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	td, err := gitService.LoadTemplateData(source, o.periodName(), since, o.compare)
+	if err != nil {
+		return err
+	}
+	summary, err := gitService.RenderSummary(td)
+	if err != nil {
+		return err
+	}
+	return writeOutput(o.output, summary+"\n")
+}
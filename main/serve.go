@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	gitService "pm/service"
+	"strings"
+	"time"
+)
+
+func runServe(args []string) error {
+	var o options
+	fs := newFlagSet("serve", "serve [--addr :8080] [--range yearly] [--refresh 15m]")
+	o.addProfileFlags(fs)
+	fs.StringVar(&o.rangeName, "range", "yearly", "period metrics and rules without a window cover: "+strings.Join(gitService.Periods, ", "))
+	addr := fs.String("addr", ":8080", "address to listen on")
+	refresh := fs.Duration("refresh", 15*time.Minute, "how often metrics are fetched again in the background")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if _, err := gitService.PeriodStart(o.rangeName, time.Now()); err != nil {
+		return usageError{err.Error()}
+	}
+	if *refresh < time.Minute {
+		return usagef("--refresh %s is too short: use at least 1m", *refresh)
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+
+	// Each refresh opens the metrics store, when enabled, only while reading
+	// it, so pm sync can keep it current in between.
+	open := func() (gitService.ReportSource, func(), error) {
+		return reportSource(profile, token)
+	}
+	server := gitService.NewBadgeServer(open, badgeRules(profile), o.rangeName, *refresh)
+	server.Start()
+	mux := http.NewServeMux()
+	mux.Handle("/badge/", server)
+	fmt.Printf("🚀 Serving shields.io endpoint badges on %s/badge/<name>.json\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
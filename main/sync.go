@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	githubclient "pm/client"
+	gitService "pm/service"
+	"pm/store"
+)
+
+func runSync(args []string) error {
+	var o options
+	fs := newFlagSet("sync", "sync [--range yearly | --since YYYY-MM-DD]")
+	o.addProfileFlags(fs)
+	o.addRangeFlags(fs, "yearly")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+	since, err := o.sinceTime(profile.Location())
+	if err != nil {
+		return err
+	}
+	if !profile.Store.Enabled {
+		fmt.Fprintln(os.Stderr, "ℹ️ The metrics store is disabled, so reports will not read what is synced: set store.enabled in the config.")
+	}
+	username, err := githubclient.GetGitHubUsername(token)
+	if err != nil {
+		return fmt.Errorf("could not retrieve GitHub username: %w", err)
+	}
+
+	st, err := store.Open(profile.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	result, err := gitService.Sync(st, token, username, since, printProgress)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Synced %d repos (%d unchanged): %d PRs, %d commits, %d reviews\n",
+		result.Repos, result.Unchanged, result.PullRequests, result.Commits, result.Reviews)
+	if result.Failed > 0 {
+		return fmt.Errorf("sync incomplete: %d failed, see the warnings above", result.Failed)
+	}
+	return nil
+}
+
+// printProgress prints a sync's progress, one line per step.
+func printProgress(msg string) {
+	fmt.Println(msg)
+}
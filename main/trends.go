@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"pm/models"
+	gitService "pm/service"
+	"pm/store"
+	"slices"
+	"strings"
+)
+
+func runTrends(args []string) error {
+	var o options
+	fs := newFlagSet("trends", "trends [--metric prs_merged] [--by week] [--last 26] [--avg 4]")
+	o.addProfileFlags(fs)
+	metric := fs.String("metric", "prs_merged", "metric to chart: "+strings.Join(gitService.MetricNames, ", "))
+	by := fs.String("by", "week", "period of each point: "+strings.Join(gitService.TrendPeriods, ", "))
+	last := fs.Int("last", 26, "number of periods to show")
+	window := fs.Int("avg", 4, "number of periods the rolling average covers")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if _, ok := gitService.MetricValue(models.ReportMetrics{}, *metric); !ok {
+		return usagef("unknown metric %q: expected one of %s", *metric, strings.Join(gitService.MetricNames, ", "))
+	}
+	if !slices.Contains(gitService.TrendPeriods, *by) {
+		return usagef("unknown --by %q: expected one of %s", *by, strings.Join(gitService.TrendPeriods, ", "))
+	}
+	if *last < 1 || *window < 1 {
+		return usagef("--last and --avg must be at least 1")
+	}
+
+	profile, err := o.loadProfile()
+	if err != nil {
+		return err
+	}
+	st, err := store.Open(profile.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	snapshots, err := st.Snapshots(gitService.SnapshotPeriod)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return fmt.Errorf("the metrics store %s has no weekly snapshots yet: run `pm sync` or `pm backfill --from YYYY-MM-DD` first", st.Path())
+	}
+	points, err := gitService.WeeklyTrend(snapshots, *metric, profile.Now(), *last, *window)
+	if err != nil {
+		return err
+	}
+	fmt.Print(gitService.FormatTrend(*metric, points, *window))
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"pm/models"
+	gitService "pm/service"
+	"pm/tui"
+	"strings"
+)
+
+func runTUI(args []string) error {
+	var o options
+	fs := newFlagSet("tui", "tui [--format text|csv] [--template path.tmpl]")
+	o.addProfileFlags(fs)
+	fs.StringVar(&o.format, "format", "text", "format of generated reports: text or csv")
+	fs.StringVar(&o.template, "template", "", "path to a text/template file used to render reports")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if o.format != "text" && o.format != "csv" {
+		return usagef("unknown format %q: expected text or csv", o.format)
+	}
+
+	profile, token, err := o.setup()
+	if err != nil {
+		return err
+	}
+
+	source, closeSource, err := reportSource(profile, token)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	defaultSince := profile.Now().AddDate(0, 0, -7)
+	defaultSummary := "No data available"
+	var changes []gitService.MetricChange
+	td, err := gitService.LoadTemplateData(source, "summary", defaultSince, true)
+	if err != nil {
+		log.Println("⚠️", err)
+	} else {
+		// The TUI shows the changes on their own, with indicators.
+		changes, td.Previous = td.Changes(), nil
+		if defaultSummary, err = gitService.RenderSummary(td); err != nil {
+			return err
+		}
+	}
+
+	var snapshots []models.MetricsSnapshot
+	if s, ok := source.(*gitService.StoreSource); ok {
+		if snapshots, err = s.Snapshots(); err != nil {
+			log.Println("⚠️", err)
+		}
+	}
+
+	period, since, ok := tui.RunWithTokenWithSummary(token, defaultSummary, changes, snapshots, profile.Now)
+	if !ok {
+		fmt.Println("No report generated.")
+		return nil
+	}
+
+	if period == "badges" {
+		badgeSince, _ := gitService.PeriodStart("yearly", profile.Now())
+		state, err := evaluateBadges(profile, token, badgeSince)
+		if err != nil {
+			return err
+		}
+		summary := gitService.FormatBadges(state.existing, state.results, "🏅 Earned:") + formatExpired(state.expired) + gitService.FormatReadmeScan(state.scan)
+
+		update, err := planProfileUpdate(profile, state)
+		if err != nil {
+			return err
+		}
+		preview := ""
+		if !update.empty() {
+			preview = "\n🆕 Pending changes:\n" + update.preview()
+		}
+
+		confirmed, err := tui.Confirm("🏅 Developer Badges", summary, preview)
+		if err != nil {
+			return err
+		}
+		if update.empty() {
+			return nil
+		}
+		if !confirmed {
+			fmt.Println("No changes written.")
+			return nil
+		}
+		_, err = update.apply()
+		return err
+	}
+
+	data, err := source.Get(since)
+	if err != nil {
+		return err
+	}
+	if o.format == "csv" {
+		dir, err := gitService.ExportCSV(data, profile.Report.OutputDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ CSV files saved to %s\n", dir)
+		return nil
+	}
+
+	report, err := gitService.RenderReport(gitService.NewTemplateData(period, data), profile.Report.Template)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(profile.Report.OutputDir, os.ModePerm); err != nil {
+		return err
+	}
+	filename := filepath.Join(profile.Report.OutputDir, fmt.Sprintf("report_%s.txt", profile.Now().Format("2006-01-02")))
+	content := fmt.Sprintf("%s report\n\n%s", strings.Title(period), report)
+	return writeOutput(filename, content)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	gitClient "pm/client"
+	"pm/config"
+	gitService "pm/service"
+	"pm/utils"
+	"slices"
+	"strings"
+)
+
+// fileChange is a pending change to one file in the profile repo.
+type fileChange struct {
+	path       string
+	oldContent string
+	newContent string
+}
+
+// profileUpdate is a set of pending changes to the profile repo.
+type profileUpdate struct {
+	profile config.Profile
+	files   profileFiles
+	changes []fileChange
+	// token and description are used to open a pull request in
+	// pull_request mode.
+	token       string
+	description string
+	// unpushed counts local commits the git writer has yet to push.
+	unpushed int
+}
+
+func (u profileUpdate) pullRequest() bool {
+	return u.profile.ProfileRepo.Mode == "pull_request"
+}
+
+// profileFiles reads the profile repo from the local clone or from GitHub.
+type profileFiles interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+type localFiles struct{}
+
+func (localFiles) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// apiFiles reads profile repo files through the GitHub API. Paths outside
+// the repo, such as an absolute ledger path, are read from disk.
+type apiFiles struct {
+	repo *gitService.APIRepo
+	root string
+}
+
+func (f apiFiles) repoPath(path string) (string, bool) {
+	rel, err := filepath.Rel(f.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (f apiFiles) ReadFile(path string) ([]byte, error) {
+	if rel, ok := f.repoPath(path); ok {
+		return f.repo.ReadFile(rel)
+	}
+	return os.ReadFile(path)
+}
+
+// openProfileFiles returns the reader for the profile's writer. The API
+// writer writes to profile_repo.repo, or the user's <login>/<login> profile repo.
+func openProfileFiles(profile config.Profile, token string) (profileFiles, error) {
+	if !profile.UsesAPIWriter() {
+		return localFiles{}, nil
+	}
+
+	name := profile.ProfileRepo.Repo
+	if name == "" {
+		login, err := gitClient.GetGitHubUsername(token)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve GitHub username: %w", err)
+		}
+		name = login + "/" + login
+	}
+	repo, err := gitService.NewAPIRepo(token, name, profile.ProfileRepo.Branch)
+	if err != nil {
+		return nil, err
+	}
+	repo.Message = profile.ProfileRepo.CommitMessage
+	if author := profile.ProfileRepo.Author; author.Name != "" {
+		repo.Author = &author
+	}
+	return apiFiles{repo: repo, root: profile.ProfileRepo.Path}, nil
+}
+
+func profileGitRepo(profile config.Profile) utils.GitRepo {
+	repo := profile.ProfileRepo
+	return utils.GitRepo{
+		Path:       repo.Path,
+		Remote:     repo.Remote,
+		Branch:     repo.Branch,
+		Message:    repo.CommitMessage,
+		Sign:       repo.Sign,
+		SigningKey: repo.SigningKey,
+	}
+}
+
+// add stages newContent for path, reading the current content.
+func (u *profileUpdate) add(path, newContent string) error {
+	oldContent, err := u.files.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	u.changes = append(u.changes, fileChange{path: path, oldContent: string(oldContent), newContent: newContent})
+	return nil
+}
+
+// empty reports whether there is nothing to write or push.
+func (u profileUpdate) empty() bool {
+	if u.unpushed > 0 {
+		return false
+	}
+	for _, c := range u.changes {
+		if c.oldContent != c.newContent {
+			return false
+		}
+	}
+	return true
+}
+
+// repoFiles returns the changed files that live in the profile repo, relative to it.
+func (u profileUpdate) repoFiles() []string {
+	var files []string
+	for _, c := range u.changes {
+		if c.oldContent == c.newContent {
+			continue
+		}
+		rel, err := filepath.Rel(u.profile.ProfileRepo.Path, c.path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		files = append(files, rel)
+	}
+	return files
+}
+
+func (u profileUpdate) displayName(path string) string {
+	if rel, err := filepath.Rel(u.profile.ProfileRepo.Path, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// preview renders a diff of every change and the git commands or API writes
+// apply would run.
+func (u profileUpdate) preview() string {
+	var b strings.Builder
+	for _, c := range u.changes {
+		name := u.displayName(c.path)
+		b.WriteString(utils.UnifiedDiff("a/"+name, "b/"+name, c.oldContent, c.newContent))
+	}
+
+	files := u.repoFiles()
+	if len(files) == 0 && u.unpushed == 0 {
+		return b.String()
+	}
+	repo := u.profile.ProfileRepo
+	branch := repo.Branch
+	if u.pullRequest() {
+		branch = gitService.BadgeBranch(u.profile.Now())
+	}
+
+	if api, ok := u.files.(apiFiles); ok {
+		fmt.Fprintf(&b, "\nGitHub API commit to %s on %s (%q):\n", api.repo.FullName(), branch, api.repo.Message)
+		for _, file := range files {
+			fmt.Fprintf(&b, "  %s\n", filepath.ToSlash(file))
+		}
+	} else {
+		if u.unpushed > 0 {
+			fmt.Fprintf(&b, "\n%d earlier commit(s) on %s were never pushed and will be pushed too.\n", u.unpushed, repo.Branch)
+		}
+		b.WriteString("\nGit commands:\n")
+		git := profileGitRepo(u.profile)
+		commands := git.PushCommands(files)
+		if u.pullRequest() {
+			commands = git.BranchCommands(files, branch)
+		}
+		for _, cmd := range commands {
+			b.WriteString("  " + strings.Join(cmd, " ") + "\n")
+		}
+	}
+	if u.pullRequest() {
+		fmt.Fprintf(&b, "\nPull request %s → %s:\n%s", branch, repo.Branch, u.description)
+	}
+	return b.String()
+}
+
+// applyResult is what apply changed.
+type applyResult struct {
+	files       []string
+	committed   bool
+	pullRequest string
+}
+
+func (u profileUpdate) apply() (applyResult, error) {
+	repo := u.profile.ProfileRepo
+	branch := gitService.BadgeBranch(u.profile.Now())
+	files := u.repoFiles()
+	result := applyResult{files: files}
+	git := profileGitRepo(u.profile)
+	api, useAPI := u.files.(apiFiles)
+
+	if useAPI && u.pullRequest() {
+		if err := gitService.EnsureBranch(u.token, api.repo.FullName(), branch, repo.Branch); err != nil {
+			return result, err
+		}
+		api.repo.SwitchBranch(branch)
+	}
+	if !useAPI && (len(files) > 0 || u.unpushed > 0) {
+		if err := git.Check(files); err != nil {
+			return result, fmt.Errorf("cannot update the profile repo: %w", err)
+		}
+	}
+
+	// The API commit goes first, so files outside the repo, such as a local
+	// ledger, are only written once the repo holds the change.
+	if useAPI && len(files) > 0 {
+		writes := map[string][]byte{}
+		for _, c := range u.changes {
+			if rel, ok := api.repoPath(c.path); ok && c.oldContent != c.newContent {
+				writes[rel] = []byte(c.newContent)
+			}
+		}
+		if err := api.repo.Commit(writes); err != nil {
+			if errors.Is(err, gitClient.ErrConflict) {
+				return result, fmt.Errorf("%w; run the command again to rebuild the change", err)
+			}
+			return result, err
+		}
+		fmt.Printf("✅ Committed %s to %s\n", strings.Join(slices.Sorted(maps.Keys(writes)), ", "), api.repo.FullName())
+		result.committed = true
+	}
+
+	for _, c := range u.changes {
+		if c.oldContent == c.newContent {
+			continue
+		}
+		if _, ok := api.repoPath(c.path); useAPI && ok {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+			return result, err
+		}
+		if err := os.WriteFile(c.path, []byte(c.newContent), 0644); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", c.path, err)
+		}
+		fmt.Println("✅ Updated", u.displayName(c.path))
+	}
+
+	if (len(files) == 0 && u.unpushed == 0) || (useAPI && !u.pullRequest()) {
+		return result, nil
+	}
+	if !u.pullRequest() {
+		committed, err := git.CommitAndPush(files)
+		result.committed = committed
+		if err != nil {
+			return result, fmt.Errorf("failed to push the profile repo: %w", err)
+		}
+		if !committed {
+			fmt.Println("ℹ️ No changes to commit.")
+		}
+		return result, nil
+	}
+
+	fullName := repo.Repo
+	if useAPI {
+		fullName = api.repo.FullName()
+	} else {
+		committed, err := git.CommitToBranch(files, branch)
+		result.committed = committed
+		if err != nil {
+			return result, fmt.Errorf("failed to push %s: %w", branch, err)
+		}
+		if !committed {
+			fmt.Println("ℹ️ No changes to commit.")
+			return result, nil
+		}
+		if fullName == "" {
+			if fullName, err = git.RemoteRepo(); err != nil {
+				return result, err
+			}
+		}
+	}
+	pr, created, err := gitService.OpenPullRequest(u.token, fullName, branch, repo.Branch, repo.CommitMessage, u.description)
+	if err != nil {
+		return result, fmt.Errorf("failed to open pull request: %w", err)
+	}
+	result.pullRequest = pr.HTMLURL
+	if created {
+		fmt.Println("🔀 Opened pull request", pr.HTMLURL)
+	} else {
+		fmt.Println("🔀 Updated pull request", pr.HTMLURL)
+	}
+	return result, nil
+}
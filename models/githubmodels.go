@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type GithubUser struct {
 	Login string `json:"login"`
 }
@@ -9,6 +11,7 @@ type GithubRepo struct {
 	FullName        string     `json:"full_name"`
 	HTMLURL         string     `json:"html_url"`
 	UpdatedAt       string     `json:"updated_at"`
+	PushedAt        string     `json:"pushed_at,omitempty"`
 	Owner           GithubUser `json:"owner"`
 	CommitCount     int        `json:"commit_count,omitempty"`
 	IssueFixCount   int        `json:"issue_fix_count,omitempty"`
@@ -22,8 +25,10 @@ type GithubRepo struct {
 type PullRequest struct {
 	Title        string     `json:"title"`
 	Body         string     `json:"body"`
+	HTMLURL      string     `json:"html_url"`
 	MergedAt     string     `json:"merged_at"`
 	CreatedAt    string     `json:"created_at"`
+	UpdatedAt    string     `json:"updated_at,omitempty"`
 	State        string     `json:"state"`
 	Number       int        `json:"number"`
 	Additions    int        `json:"additions"`
@@ -31,3 +36,177 @@ type PullRequest struct {
 	ChangedFiles int        `json:"changed_files"`
 	User         GithubUser `json:"user"`
 }
+
+type CommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Date  string `json:"date"`
+}
+
+type CommitDetail struct {
+	Message string       `json:"message"`
+	Author  CommitAuthor `json:"author"`
+}
+
+type Commit struct {
+	SHA     string       `json:"sha"`
+	HTMLURL string       `json:"html_url"`
+	Commit  CommitDetail `json:"commit"`
+}
+
+// ReviewedPR is a pull request by someone else that the user reviewed, as
+// returned by the issue search API.
+type ReviewedPR struct {
+	Title         string `json:"title"`
+	HTMLURL       string `json:"html_url"`
+	RepositoryURL string `json:"repository_url"`
+	Number        int    `json:"number"`
+	UpdatedAt     string `json:"updated_at"`
+	// ReviewedAt is when the user first reviewed the pull request. Sync fills
+	// it in; live reports leave it empty.
+	ReviewedAt string `json:"reviewed_at,omitempty"`
+}
+
+// ReviewTime is when the review happened: ReviewedAt, or UpdatedAt when that
+// is unknown.
+func (pr ReviewedPR) ReviewTime() string {
+	if pr.ReviewedAt != "" {
+		return pr.ReviewedAt
+	}
+	return pr.UpdatedAt
+}
+
+// RepoActivity groups everything fetched for a single repository in a report window.
+type RepoActivity struct {
+	Repo         GithubRepo
+	Languages    map[string]int
+	PullRequests []PullRequest
+	Commits      []Commit
+}
+
+// ReportData is the raw data a report is built from.
+type ReportData struct {
+	Username    string
+	Since       time.Time
+	GeneratedAt time.Time
+	Repos       []RepoActivity
+	Reviews     int
+	ReviewedPRs []ReviewedPR
+}
+
+// ReportMetrics holds the totals computed from a ReportData.
+type ReportMetrics struct {
+	Repositories      int            `json:"repositories"`
+	PRsMerged         int            `json:"prs_merged"`
+	Commits           int            `json:"commits"`
+	Additions         int            `json:"additions"`
+	Deletions         int            `json:"deletions"`
+	ChangedFiles      int            `json:"changed_files"`
+	IssuesFixed       int            `json:"issues_fixed"`
+	Reviews           int            `json:"reviews"`
+	Stars             int            `json:"stars"`
+	Forks             int            `json:"forks"`
+	AvgTimeToMerge    time.Duration  `json:"avg_time_to_merge"`
+	MedianTimeToMerge time.Duration  `json:"median_time_to_merge"`
+	Languages         map[string]int `json:"languages"`
+}
+
+// MetricsSnapshot is the metrics of one period, e.g. the week starting Start,
+// computed from stored activity.
+type MetricsSnapshot struct {
+	Period     string        `json:"period"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	ComputedAt time.Time     `json:"computed_at"`
+	Metrics    ReportMetrics `json:"metrics"`
+}
+
+// BadgeTier is one level of a tiered badge, e.g. silver at 10 merged PRs.
+type BadgeTier struct {
+	Name      string  `yaml:"name" json:"name"`
+	Threshold float64 `yaml:"threshold" json:"threshold"`
+	Color     string  `yaml:"color" json:"color"`
+}
+
+// BadgeRule declares a badge awarded when Metric, computed over Window,
+// compares true against Threshold, or against each of Tiers in turn. A rule
+// with Expires loses its badge once it no longer holds, unless the badge was
+// earned within the Expires window.
+type BadgeRule struct {
+	ID         string      `yaml:"id" json:"id"`
+	Name       string      `yaml:"name" json:"name"`
+	Label      string      `yaml:"label" json:"label"`
+	Icon       string      `yaml:"icon" json:"icon"`
+	Message    string      `yaml:"message" json:"message"`
+	Color      string      `yaml:"color" json:"color"`
+	Metric     string      `yaml:"metric" json:"metric"`
+	Comparator string      `yaml:"comparator" json:"comparator"`
+	Threshold  float64     `yaml:"threshold" json:"threshold"`
+	Tiers      []BadgeTier `yaml:"tiers" json:"tiers,omitempty"`
+	Window     string      `yaml:"window" json:"window"`
+	Expires    string      `yaml:"expires" json:"expires,omitempty"`
+}
+
+// BadgeLedgerEntry records a badge tier being earned. ExpiredAt is set when
+// the badge expires; the entry stays in the ledger as history.
+type BadgeLedgerEntry struct {
+	ID        string     `json:"id"`
+	Tier      string     `json:"tier,omitempty"`
+	EarnedAt  time.Time  `json:"earned_at"`
+	Value     float64    `json:"value"`
+	Evidence  string     `json:"evidence,omitempty"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+}
+
+// BadgeLedger is the persisted history of earned badges.
+type BadgeLedger struct {
+	Version int                `json:"version"`
+	Entries []BadgeLedgerEntry `json:"entries"`
+}
+
+// RepoContent is a file as returned by the Contents API. Content is base64.
+type RepoContent struct {
+	Path     string `json:"path"`
+	SHA      string `json:"sha"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type CommitIdentity struct {
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email" yaml:"email"`
+}
+
+// TreeEntry is a file of a Git Data API tree, given by its text content.
+type TreeEntry struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// NewCommit is the body of a Git Data API commit.
+type NewCommit struct {
+	Message string          `json:"message"`
+	Tree    string          `json:"tree"`
+	Parents []string        `json:"parents"`
+	Author  *CommitIdentity `json:"author,omitempty"`
+}
+
+// NewPullRequest is the body of a pull request create or update.
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head,omitempty"`
+	Base  string `json:"base,omitempty"`
+	Body  string `json:"body"`
+}
+
+// ShieldsEndpoint is the JSON read by shields.io's endpoint badge.
+type ShieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color,omitempty"`
+	IsError       bool   `json:"isError,omitempty"`
+	CacheSeconds  int    `json:"cacheSeconds,omitempty"`
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"pm/models"
+	"pm/store"
+	"time"
+)
+
+// Backfill syncs username's activity back to the Monday of from's week, then
+// returns the weekly snapshots from that week on, oldest first. Each
+// repository's merged pull requests and commits are listed once for the whole
+// range and the reviews searched a window at a time, so the weeks cost no
+// extra requests. The sync cursors are the checkpoint: each repository and
+// review window synced keeps its progress, and running Backfill again after a
+// failure only fetches the rest. progress is told of each one.
+func Backfill(st *store.Store, token, username string, from time.Time, progress func(string)) ([]models.MetricsSnapshot, error) {
+	from = WeekStart(from)
+	result, err := Sync(st, token, username, from, progress)
+	if err != nil {
+		return nil, err
+	}
+	if result.Failed > 0 {
+		return nil, fmt.Errorf("backfill incomplete: %d failed, see the warnings above", result.Failed)
+	}
+
+	snapshots, err := st.Snapshots(SnapshotPeriod)
+	if err != nil {
+		return nil, err
+	}
+	var backfilled []models.MetricsSnapshot
+	for _, snapshot := range snapshots {
+		if !snapshot.Start.Before(from) {
+			backfilled = append(backfilled, snapshot)
+		}
+	}
+	return backfilled, nil
+}
@@ -1,99 +1,477 @@
 package service
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"pm/client"
+	"pm/models"
+	"pm/svg"
+	"pm/utils"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func GetBadgesFromReadme(readmePath string) ([]string, []string, error) {
+// DefaultBadgeRules are used when the config declares no badge rules.
+var DefaultBadgeRules = []models.BadgeRule{
+	{ID: "first-pr", Name: "First PR", Label: "1st PR", Icon: "🎉", Message: "achieved", Color: "green", Metric: "prs_merged", Comparator: ">=", Threshold: 1},
+	{ID: "first-repo", Name: "First Repo", Label: "1st Repo", Icon: "📁", Message: "active", Color: "blue", Metric: "repositories", Comparator: ">=", Threshold: 1},
+}
+
+// tierColors are used for well-known tier names without an explicit color.
+var tierColors = map[string]string{
+	"bronze":   "cd7f32",
+	"silver":   "c0c0c0",
+	"gold":     "ffd700",
+	"platinum": "e5e4e2",
+}
+
+var comparators = map[string]func(value, threshold float64) bool{
+	">=": func(v, t float64) bool { return v >= t },
+	">":  func(v, t float64) bool { return v > t },
+	"==": func(v, t float64) bool { return v == t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"<":  func(v, t float64) bool { return v < t },
+}
+
+var metricUnits = map[string]string{
+	"repositories":       "repos",
+	"prs_merged":         "PRs merged",
+	"changed_files":      "files changed",
+	"issues_fixed":       "issues fixed",
+	"avg_hours_to_merge": "hours to merge",
+}
 
-	content, err := getReadMeContent(readmePath)
-	if err != nil {
-		log.Fatalf("Failed to read README: %v", err)
+// BadgeResult is the outcome of evaluating one rule. Tier is the index of the
+// highest tier earned, or -1.
+type BadgeResult struct {
+	Rule     models.BadgeRule
+	Value    float64
+	Tier     int
+	Evidence string
+}
+
+func (r BadgeResult) Earned() bool {
+	return r.Tier >= 0
+}
+
+// Badge is the README markdown for the earned tier.
+func (r BadgeResult) Badge() string {
+	if !r.Earned() {
+		return ""
 	}
+	return BadgeMarkdown(r.Rule, r.Tier)
+}
 
-	existingBadges := []string{}
-	newBadges := []string{}
+// Progress describes the earned tier and the distance to the next one, e.g.
+// "Silver Reviewer: 37/50 reviews".
+func (r BadgeResult) Progress() string {
+	tiers := ruleTiers(r.Rule)
+	value := formatMetric(r.Value)
+	unit := metricUnit(r.Rule.Metric)
+
+	title := r.Rule.Label
+	if r.Earned() {
+		title = tierTitle(r.Rule, r.Tier)
+	}
+	if r.Tier+1 < len(tiers) {
+		return fmt.Sprintf("%s: %s/%s %s", title, value, formatMetric(tiers[r.Tier+1].Threshold), unit)
+	}
+	return fmt.Sprintf("%s: %s %s (top tier)", title, value, unit)
+}
+
+func ValidateBadgeRules(rules []models.BadgeRule) error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, rule := range rules {
+		if rule.ID == "" {
+			errs = append(errs, fmt.Errorf("badge rule %d: missing id", i+1))
+		} else if seen[rule.ID] {
+			errs = append(errs, fmt.Errorf("badge rule %q: duplicate id", rule.ID))
+		}
+		seen[rule.ID] = true
+
+		if rule.Label == "" {
+			errs = append(errs, fmt.Errorf("badge rule %q: missing label", rule.ID))
+		}
+		if _, ok := MetricValue(models.ReportMetrics{}, rule.Metric); !ok {
+			errs = append(errs, fmt.Errorf("badge rule %q: unknown metric %q (expected one of %s)", rule.ID, rule.Metric, strings.Join(MetricNames, ", ")))
+		}
+		comparator := ruleComparator(rule)
+		if _, ok := comparators[comparator]; !ok {
+			errs = append(errs, fmt.Errorf("badge rule %q: unknown comparator %q", rule.ID, rule.Comparator))
+		}
+		if len(rule.Tiers) > 0 && comparator != ">=" && comparator != ">" {
+			errs = append(errs, fmt.Errorf("badge rule %q: tiers require a >= or > comparator", rule.ID))
+		}
+		for j, tier := range rule.Tiers {
+			if tier.Name == "" {
+				errs = append(errs, fmt.Errorf("badge rule %q: tier %d has no name", rule.ID, j+1))
+			}
+			if j > 0 && tier.Threshold <= rule.Tiers[j-1].Threshold {
+				errs = append(errs, fmt.Errorf("badge rule %q: tier %q threshold must be above %q", rule.ID, tier.Name, rule.Tiers[j-1].Name))
+			}
+		}
+		if rule.Window != "" {
+			if _, err := WindowStart(rule.Window, time.Now()); err != nil {
+				errs = append(errs, fmt.Errorf("badge rule %q: %w", rule.ID, err))
+			}
+		}
+		if rule.Expires == "all" {
+			errs = append(errs, fmt.Errorf("badge rule %q: expires cannot be \"all\"", rule.ID))
+		} else if rule.Expires != "" {
+			if _, err := WindowStart(rule.Expires, time.Now()); err != nil {
+				errs = append(errs, fmt.Errorf("badge rule %q: expires: %w", rule.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	if strings.Contains(content, "1st%20PR-achieved") {
-		existingBadges = append(existingBadges, "🎉 1st PR Badge")
+func ruleComparator(rule models.BadgeRule) string {
+	if rule.Comparator == "" {
+		return ">="
 	}
-	if strings.Contains(content, "1st%20Repo-active") {
-		existingBadges = append(existingBadges, "📁 1st Repo Badge")
+	return rule.Comparator
+}
+
+// ruleTiers returns the rule's tiers, treating an untiered rule as a single
+// unnamed tier at Threshold.
+func ruleTiers(rule models.BadgeRule) []models.BadgeTier {
+	if len(rule.Tiers) > 0 {
+		return rule.Tiers
+	}
+	return []models.BadgeTier{{Threshold: rule.Threshold, Color: rule.Color}}
+}
+
+// BadgeMarkdown renders the shields.io README image for a rule at the given tier.
+func BadgeMarkdown(rule models.BadgeRule, tier int) string {
+	return fmt.Sprintf("![%s](https://img.shields.io/badge/%s-%s-%s)", badgeAlt(rule, tier), shieldsEscape(iconLabel(rule)), shieldsEscape(badgeMessage(rule, tier)), badgeColor(rule, tier))
+}
+
+// Badge renderers: self-hosted SVG files in the profile repo, or shields.io URLs.
+const (
+	RendererSVG     = "svg"
+	RendererShields = "shields"
+)
+
+// BadgeFile is the path of a rule's SVG badge, relative to the README.
+func BadgeFile(rule models.BadgeRule) string {
+	return "badges/" + rule.ID + ".svg"
+}
+
+// BadgeSVG renders a rule's badge at the given tier as a standalone SVG.
+func BadgeSVG(rule models.BadgeRule, tier int, style svg.Style) string {
+	return svg.Badge(iconLabel(rule), badgeMessage(rule, tier), badgeColor(rule, tier), style)
+}
+
+// BadgeImage renders the README markdown for a rule's badge with renderer.
+func BadgeImage(rule models.BadgeRule, tier int, renderer string) string {
+	if renderer == RendererShields {
+		return BadgeMarkdown(rule, tier)
+	}
+	return fmt.Sprintf("![%s](%s)", badgeAlt(rule, tier), BadgeFile(rule))
+}
+
+func badgeAlt(rule models.BadgeRule, tier int) string {
+	name := rule.Name
+	if name == "" {
+		name = rule.Label
+	}
+	if len(rule.Tiers) > 0 {
+		name = fmt.Sprintf("%s (%s)", name, rule.Tiers[tier].Name)
 	}
+	return name
+}
 
-	return existingBadges, newBadges, nil
+func iconLabel(rule models.BadgeRule) string {
+	if rule.Icon == "" {
+		return rule.Label
+	}
+	return rule.Icon + " " + rule.Label
 }
 
-func getReadMeContent(readmePath string) (string, error) {
-	readmeContent, err := os.ReadFile(readmePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read README: %w", err)
+func badgeMessage(rule models.BadgeRule, tier int) string {
+	if len(rule.Tiers) > 0 {
+		return rule.Tiers[tier].Name
 	}
-	return string(readmeContent), nil
+	if rule.Message == "" {
+		return "achieved"
+	}
+	return rule.Message
 }
 
-func DisplayExistingBadges(badges []string, label string) {
-	fmt.Printf("\n%s\n", label)
+func badgeColor(rule models.BadgeRule, tier int) string {
+	if len(rule.Tiers) > 0 {
+		t := rule.Tiers[tier]
+		if t.Color != "" {
+			return t.Color
+		}
+		if color, ok := tierColors[strings.ToLower(t.Name)]; ok {
+			return color
+		}
+	}
+	if rule.Color == "" {
+		return "green"
+	}
+	return rule.Color
+}
+
+func shieldsEscape(s string) string {
+	return strings.NewReplacer("-", "--", "_", "__", " ", "%20").Replace(s)
+}
+
+func tierTitle(rule models.BadgeRule, tier int) string {
+	if len(rule.Tiers) == 0 {
+		return rule.Label
+	}
+	name := rule.Tiers[tier].Name
+	return strings.ToUpper(name[:1]) + name[1:] + " " + rule.Label
+}
+
+func badgeTitle(rule models.BadgeRule, tier int) string {
+	return strings.TrimSpace(rule.Icon + " " + tierTitle(rule, tier) + " Badge")
+}
+
+func metricUnit(metric string) string {
+	if unit, ok := metricUnits[metric]; ok {
+		return unit
+	}
+	return metric
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// GetBadgesFromContent lists the badges recorded in the ledger with the date
+// they were earned, shown in loc, and the ones the README content does not
+// show yet. Badges found in the README but missing from the ledger, e.g.
+// awarded before the ledger existed, are imported into it.
+func GetBadgesFromContent(content string, rules []models.BadgeRule, ledger *models.BadgeLedger, loc *time.Location) ([]string, []string) {
+	scan := ScanReadmeBadges(content, rules, *ledger)
+
+	existingBadges := []string{}
+	newBadges := []string{}
+
+	for _, rule := range rules {
+		// Expired badges still shown in the README are stale, not imported.
+		if entry, ok := ledgerEntry(*ledger, rule.ID); ledgerTier(*ledger, rule) < 0 && (!ok || entry.ExpiredAt == nil) {
+			if tier := scan.highestTier(rule); tier >= 0 {
+				recordBadge(ledger, rule, tier, 0, "found in README", time.Now())
+			}
+		}
+		tier := ledgerTier(*ledger, rule)
+		if tier < 0 {
+			continue
+		}
+		entry, _ := ledgerEntry(*ledger, rule.ID)
+		existingBadges = append(existingBadges, fmt.Sprintf("%s (earned %s)", badgeTitle(rule, tier), entry.EarnedAt.In(loc).Format("2006-01-02")))
+		if scan.highestTier(rule) != tier {
+			newBadges = append(newBadges, badgeTitle(rule, tier))
+		}
+	}
+
+	return existingBadges, newBadges
+}
+
+// DisplayExistingBadges prints badges followed by progress toward each rule's next tier.
+func DisplayExistingBadges(badges []string, results []BadgeResult, label string) {
+	fmt.Print(FormatBadges(badges, results, label))
+}
+
+func FormatBadges(badges []string, results []BadgeResult, label string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n", label)
 	if len(badges) == 0 {
-		fmt.Println("- None yet")
+		b.WriteString("- None yet\n")
 	} else {
 		for _, badge := range badges {
-			fmt.Println("-", badge)
+			fmt.Fprintln(&b, "-", badge)
 		}
 	}
+
+	if len(results) > 0 {
+		b.WriteString("\n📈 Progress:\n")
+		for _, result := range results {
+			fmt.Fprintln(&b, "-", result.Progress())
+		}
+	}
+	return b.String()
 }
 
-func firstPrBadge(token string, since time.Time, readmePath string) bool {
-	// Calculate total PRs in the time window
-	totalPRs, err := CalculateTotalPRs(token, since)
-	if err != nil {
-		log.Printf("Error calculating total PRs: %v", err)
-		return false
+// BadgeSummaryMarkdown renders new, expired and earned badges with progress as
+// Markdown, e.g. for a CI job summary.
+func BadgeSummaryMarkdown(badges, newBadges, expired []string, results []BadgeResult) string {
+	var b strings.Builder
+	b.WriteString("## 🏅 Profile badges\n\n")
+	if len(newBadges) == 0 {
+		b.WriteString("No new badges.\n")
+	} else {
+		b.WriteString("**New:**\n\n")
+		for _, badge := range newBadges {
+			fmt.Fprintln(&b, "-", badge)
+		}
+	}
+	if len(expired) > 0 {
+		b.WriteString("\n**Expired:**\n\n")
+		for _, badge := range expired {
+			fmt.Fprintln(&b, "-", badge)
+		}
 	}
 
-	// Read README content
-	readMeContent, err := getReadMeContent(readmePath)
-	if err != nil {
-		log.Printf("Failed to read README: %v", err)
-		// Still allow badge if README can't be read — up to you
-		return totalPRs >= 1 // optional: you could choose to return false here instead
+	if len(badges) > 0 {
+		b.WriteString("\n### Earned\n\n")
+		for _, badge := range badges {
+			fmt.Fprintln(&b, "-", badge)
+		}
 	}
+	if len(results) > 0 {
+		b.WriteString("\n### Progress\n\n")
+		for _, result := range results {
+			fmt.Fprintln(&b, "-", result.Progress())
+		}
+	}
+	return b.String()
+}
+
+// EvaluateBadgeRules computes each rule's metric over its window, falling back
+// to since for rules without one. With a ReportDataCache as the source, data
+// is fetched once per distinct window.
+func EvaluateBadgeRules(source ReportSource, rules []models.BadgeRule, since time.Time) ([]BadgeResult, error) {
+	now := time.Now()
+
+	var results []BadgeResult
+	for _, rule := range rules {
+		start := since
+		if rule.Window != "" {
+			var err error
+			if start, err = WindowStart(rule.Window, now); err != nil {
+				return nil, fmt.Errorf("badge rule %q: %w", rule.ID, err)
+			}
+		}
+
+		data, err := source.Get(start)
+		if err != nil {
+			return nil, err
+		}
 
-	// Badge condition: user has at least 1 PR and doesn't already have badge
-	return totalPRs >= 1 && !strings.Contains(readMeContent, "1st%20PR-achieved")
+		result, err := evaluateBadgeRule(rule, data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
 }
 
-func firstRepoBadge(token string, since time.Time, readmePath string) bool {
-	userRepos, err := client.GetUserRepos(token, since)
-	if err != nil {
-		log.Printf("Error getting user repos: %v", err)
-		return false
+func evaluateBadgeRule(rule models.BadgeRule, data models.ReportData) (BadgeResult, error) {
+	compare, ok := comparators[ruleComparator(rule)]
+	if !ok {
+		return BadgeResult{}, fmt.Errorf("badge rule %q: unknown comparator %q", rule.ID, rule.Comparator)
 	}
 
-	readMeContent, err := getReadMeContent(readmePath)
-	if err != nil {
-		log.Printf("Failed to read README: %v", err)
-		// You can decide to return false here or assume the badge isn't present
-		return len(userRepos) >= 1
+	value, _ := MetricValue(ComputeMetrics(data), rule.Metric)
+	result := BadgeResult{Rule: rule, Value: value, Tier: -1}
+	for tier, t := range ruleTiers(rule) {
+		if compare(value, t.Threshold) {
+			result.Tier = tier
+		}
+	}
+	if result.Earned() {
+		result.Evidence = metricEvidence(data, rule.Metric, ruleTiers(rule)[result.Tier].Threshold)
 	}
+	return result, nil
+}
 
-	return len(userRepos) >= 1 && !strings.Contains(readMeContent, "1st%20Repo-active")
+// metricEvidence points at the item that made a count metric reach threshold,
+// e.g. the URL of the 10th merged PR.
+func metricEvidence(data models.ReportData, metric string, threshold float64) string {
+	var urls []string
+	var times []string
+	switch metric {
+	case "prs_merged":
+		for _, activity := range data.Repos {
+			for _, pr := range activity.PullRequests {
+				urls, times = append(urls, pr.HTMLURL), append(times, pr.MergedAt)
+			}
+		}
+	case "commits":
+		for _, activity := range data.Repos {
+			for _, c := range activity.Commits {
+				urls, times = append(urls, c.HTMLURL), append(times, c.Commit.Author.Date)
+			}
+		}
+	case "repositories":
+		for _, activity := range data.Repos {
+			urls, times = append(urls, activity.Repo.HTMLURL), append(times, activity.Repo.UpdatedAt)
+		}
+	default:
+		return ""
+	}
+
+	index := int(threshold) - 1
+	if index < 0 || index >= len(urls) {
+		return ""
+	}
+	order := make([]int, len(urls))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return times[order[i]] < times[order[j]] })
+	return urls[order[index]]
 }
 
-func GetNewBadges(token string, badges []string, since time.Time, readmePath string) (newBadges []string) {
-	// Determine which badges should be added
-	if firstPrBadge(token, since, readmePath) {
-		badges = append(badges, "![First PR](https://img.shields.io/badge/🎉%201st%20PR-achieved-green)")
+// GetNewBadges records badges earned in results that the ledger does not hold
+// yet, including upgrades to a higher tier, and returns their titles appended
+// to badges. Badges of rules with an expiry window that no longer hold are
+// marked expired in the ledger and returned as removed.
+func GetNewBadges(results []BadgeResult, ledger *models.BadgeLedger, badges []string) (newBadges, removed []string) {
+	now := time.Now()
+	for _, result := range results {
+		if tier := expireBadge(ledger, result, now); tier >= 0 {
+			removed = append(removed, badgeTitle(result.Rule, tier))
+		}
+		if result.Earned() && ledgerTier(*ledger, result.Rule) < result.Tier {
+			recordBadge(ledger, result.Rule, result.Tier, result.Value, result.Evidence, now)
+			badges = append(badges, badgeTitle(result.Rule, result.Tier))
+		}
 	}
+	return badges, removed
+}
 
-	if firstRepoBadge(token, since, readmePath) {
-		badges = append(badges, "![First Repo](https://img.shields.io/badge/📁%201st%20Repo-active-blue)")
+// UpdateBadgeBlock regenerates the README's managed badge block with badges,
+// the complete set of pm badges to show. Stale and duplicated pm badges are
+// dropped, including ones on their own line outside the block, while foreign
+// badges are kept.
+func UpdateBadgeBlock(content string, rules []models.BadgeRule, badges []string) string {
+	existing, _ := utils.BadgeBlock(content)
+	content = utils.RemoveStandaloneLines(content, standaloneBadges(content, rules))
 
+	var kept []string
+	for _, line := range existing {
+		if !managedLine(line, rules) {
+			kept = append(kept, line)
+		}
+	}
+	return utils.SetBadgeBlock(content, append(kept, badges...))
+}
+
+// standaloneBadges finds lines holding nothing but pm badges, such as the ones
+// appended to the README before the managed block existed. Code blocks are
+// skipped.
+func standaloneBadges(content string, rules []models.BadgeRule) []string {
+	lines := strings.Split(content, "\n")
+	var found []string
+	seen := map[int]bool{}
+	for _, img := range utils.ParseImages(content) {
+		if seen[img.Line] {
+			continue
+		}
+		seen[img.Line] = true
+		if line := lines[img.Line-1]; managedLine(line, rules) {
+			found = append(found, strings.TrimSpace(line))
+		}
 	}
-	return badges
+	return found
 }
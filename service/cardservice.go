@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"pm/models"
+	"pm/svg"
+	"time"
+)
+
+// languageColors follows GitHub's linguist colors for common languages.
+var languageColors = map[string]string{
+	"Go":         "#00add8",
+	"Python":     "#3572a5",
+	"JavaScript": "#f1e05a",
+	"TypeScript": "#3178c6",
+	"Java":       "#b07219",
+	"Kotlin":     "#a97bff",
+	"Rust":       "#dea584",
+	"C":          "#555555",
+	"C++":        "#f34b7d",
+	"C#":         "#178600",
+	"Ruby":       "#701516",
+	"PHP":        "#4f5d95",
+	"Swift":      "#f05138",
+	"Dart":       "#00b4ab",
+	"Shell":      "#89e051",
+	"HTML":       "#e34c26",
+	"CSS":        "#563d7c",
+	"Dockerfile": "#384d54",
+	"Makefile":   "#427819",
+}
+
+const otherLanguageColor = "#8b8b8b"
+
+// CardStats summarizes data for the stats card, keeping the top n languages.
+func CardStats(data models.ReportData, n int) svg.CardStats {
+	metrics := ComputeMetrics(data)
+	stats := svg.CardStats{
+		Title:     fmt.Sprintf("%s's GitHub Stats", data.Username),
+		PRsMerged: metrics.PRsMerged,
+		Commits:   metrics.Commits,
+		Reviews:   metrics.Reviews,
+	}
+	stats.CurrentStreak, stats.LongestStreak = commitStreaks(data, time.Now().In(data.Since.Location()))
+
+	total := 0
+	for _, bytes := range metrics.Languages {
+		total += bytes
+	}
+	if total == 0 {
+		return stats
+	}
+	for _, lang := range topLanguages(n, metrics.Languages) {
+		color, ok := languageColors[lang]
+		if !ok {
+			color = otherLanguageColor
+		}
+		stats.Languages = append(stats.Languages, svg.LanguageShare{
+			Name:    lang,
+			Percent: float64(metrics.Languages[lang]) * 100 / float64(total),
+			Color:   color,
+		})
+	}
+	return stats
+}
+
+// commitStreaks returns the current and longest runs of consecutive days with
+// at least one commit. The current streak survives until the end of the day
+// after the last commit.
+func commitStreaks(data models.ReportData, now time.Time) (current, longest int) {
+	days := map[string]bool{}
+	first := now
+	for _, repo := range data.Repos {
+		for _, c := range repo.Commits {
+			date, err := time.Parse(time.RFC3339, c.Commit.Author.Date)
+			if err != nil {
+				continue
+			}
+			date = date.In(now.Location())
+			days[date.Format("2006-01-02")] = true
+			if date.Before(first) {
+				first = date
+			}
+		}
+	}
+	if len(days) == 0 {
+		return 0, 0
+	}
+
+	run := 0
+	first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, now.Location())
+	for day := first; !day.After(now); day = day.AddDate(0, 0, 1) {
+		if days[day.Format("2006-01-02")] {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	day := now
+	if !days[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day.Format("2006-01-02")] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
+// BuildStatsCard renders the stats card for data in the named theme.
+func BuildStatsCard(data models.ReportData, theme string, languages int) (string, error) {
+	t, ok := svg.Themes[theme]
+	if !ok {
+		return "", fmt.Errorf("unknown stats card theme %q: expected light or dark", theme)
+	}
+	return svg.StatsCard(CardStats(data, languages), t), nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"pm/models"
+	"time"
+)
+
+// ComparedMetrics are the metrics compared with the previous period.
+var ComparedMetrics = []string{"prs_merged", "commits", "median_hours_to_merge", "reviews"}
+
+// MetricChange is a metric's value next to its value in the previous period.
+type MetricChange struct {
+	Name     string
+	Value    float64
+	Previous float64
+}
+
+func (c MetricChange) Label() string {
+	return MetricLabel(c.Name)
+}
+
+func (c MetricChange) Delta() float64 {
+	return c.Value - c.Previous
+}
+
+// Percent returns the change relative to the previous value; ok is false when
+// the previous value is zero.
+func (c MetricChange) Percent() (percent float64, ok bool) {
+	if c.Previous == 0 {
+		return 0, false
+	}
+	return c.Delta() * 100 / c.Previous, true
+}
+
+// Indicator is ▲ or ▼ for a rise or fall, or • when the value held.
+func (c MetricChange) Indicator() string {
+	switch {
+	case c.Delta() > 0:
+		return "▲"
+	case c.Delta() < 0:
+		return "▼"
+	}
+	return "•"
+}
+
+// String formats the change as e.g. "+3 (+42.9%)", "-2.5h" or "no change".
+func (c MetricChange) String() string {
+	delta := c.Delta()
+	if delta == 0 {
+		return "no change"
+	}
+	s := FormatMetricValue(c.Name, delta)
+	if delta > 0 {
+		s = "+" + s
+	}
+	if percent, ok := c.Percent(); ok {
+		s += fmt.Sprintf(" (%+.1f%%)", percent)
+	}
+	return s
+}
+
+// CompareMetrics returns the change of each of ComparedMetrics.
+func CompareMetrics(current, previous models.ReportMetrics) []MetricChange {
+	changes := make([]MetricChange, 0, len(ComparedMetrics))
+	for _, name := range ComparedMetrics {
+		value, _ := MetricValue(current, name)
+		before, _ := MetricValue(previous, name)
+		changes = append(changes, MetricChange{Name: name, Value: value, Previous: before})
+	}
+	return changes
+}
+
+// GetComparison returns the report data since since and that of the
+// equally long period just before it, from a single fetch.
+func GetComparison(source ReportSource, since time.Time) (current, previous models.ReportData, err error) {
+	previousSince := since.Add(-time.Since(since))
+	data, err := source.Get(previousSince)
+	if err != nil {
+		return current, previous, err
+	}
+	return SliceReportData(data, since, data.GeneratedAt), SliceReportData(data, previousSince, since), nil
+}
+
+// SliceReportData returns the part of data in [since, until): the pull
+// requests merged, commits authored and reviewed pull requests updated in it,
+// and the repositories updated or active in it.
+func SliceReportData(data models.ReportData, since, until time.Time) models.ReportData {
+	slice := models.ReportData{Username: data.Username, Since: since, GeneratedAt: until}
+	for _, activity := range data.Repos {
+		sliced := models.RepoActivity{Repo: activity.Repo, Languages: activity.Languages}
+		for _, pr := range activity.PullRequests {
+			if inPeriod(pr.MergedAt, since, until) {
+				sliced.PullRequests = append(sliced.PullRequests, pr)
+			}
+		}
+		for _, c := range activity.Commits {
+			if inPeriod(c.Commit.Author.Date, since, until) {
+				sliced.Commits = append(sliced.Commits, c)
+			}
+		}
+		if repoActive(sliced, since, until) {
+			slice.Repos = append(slice.Repos, sliced)
+		}
+	}
+	for _, pr := range data.ReviewedPRs {
+		if inPeriod(pr.ReviewTime(), since, until) {
+			slice.ReviewedPRs = append(slice.ReviewedPRs, pr)
+		}
+	}
+	slice.Reviews = len(slice.ReviewedPRs)
+	return slice
+}
+
+// repoActive reports whether a repository was updated in [since, until) or
+// holds activity from it.
+func repoActive(activity models.RepoActivity, since, until time.Time) bool {
+	return inPeriod(activity.Repo.UpdatedAt, since, until) || len(activity.PullRequests) > 0 || len(activity.Commits) > 0
+}
+
+// inPeriod reports whether the RFC 3339 timestamp ts is in [since, until).
+func inPeriod(ts string, since, until time.Time) bool {
+	t, err := time.Parse(time.RFC3339, ts)
+	return err == nil && !t.Before(since) && t.Before(until)
+}
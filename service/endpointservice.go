@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"pm/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryBackoff is how long a BadgeServer first waits to fetch again after a
+// failed refresh; the wait doubles with each failure in a row, up to the TTL.
+const retryBackoff = time.Minute
+
+// BadgeServer serves shields.io endpoint JSON for each badge rule and metric
+// at /badge/<name>.json, where metric names use dashes, e.g. prs-merged.
+// Values come from the last snapshot Start collected; requests never wait for
+// GitHub or the metrics store.
+type BadgeServer struct {
+	open   func() (ReportSource, func(), error)
+	rules  []models.BadgeRule
+	period string
+	ttl    time.Duration
+
+	mu       sync.RWMutex
+	snapshot badgeSnapshot
+}
+
+type badgeSnapshot struct {
+	metrics models.ReportMetrics
+	results []BadgeResult
+	at      time.Time
+}
+
+// NewBadgeServer returns a server whose snapshots are computed from the
+// source open returns on each refresh, such as the metrics store, and closed
+// after it.
+func NewBadgeServer(open func() (ReportSource, func(), error), rules []models.BadgeRule, period string, ttl time.Duration) *BadgeServer {
+	return &BadgeServer{open: open, rules: rules, period: period, ttl: ttl}
+}
+
+// Start collects a snapshot, then keeps refreshing it in the background every
+// TTL. When a refresh fails the previous snapshot stays served and the next
+// attempt waits for a backoff instead.
+func (s *BadgeServer) Start() {
+	go func() {
+		var backoff time.Duration
+		for {
+			wait := s.ttl
+			if err := s.refresh(); err != nil {
+				backoff = min(max(2*backoff, retryBackoff), s.ttl)
+				wait = backoff
+				if s.current().at.IsZero() {
+					log.Printf("⚠️ Failed to collect badge data, retrying in %s: %v", wait, err)
+				} else {
+					log.Printf("⚠️ Failed to refresh badge data, serving the previous values and retrying in %s: %v", wait, err)
+				}
+			} else {
+				backoff = 0
+			}
+			time.Sleep(wait)
+		}
+	}()
+}
+
+// refresh collects fresh data and replaces the snapshot with it. Rule windows
+// and the period are resolved at collection time.
+func (s *BadgeServer) refresh() error {
+	now := time.Now()
+	since, err := PeriodStart(s.period, now)
+	if err != nil {
+		return err
+	}
+	source, closeSource, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+	data, err := source.Get(since)
+	if err != nil {
+		return err
+	}
+	results, err := EvaluateBadgeRules(source, s.rules, since)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = badgeSnapshot{metrics: ComputeMetrics(data), results: results, at: now}
+	return nil
+}
+
+// current returns the last collected snapshot, zero before the first.
+func (s *BadgeServer) current() badgeSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+func (s *BadgeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, "/badge/")
+	if name, ok = strings.CutSuffix(name, ".json"); !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !s.known(name) {
+		writeEndpoint(w, http.StatusNotFound, models.ShieldsEndpoint{Label: name, Message: "unknown badge", Color: "lightgrey", IsError: true})
+		return
+	}
+	snapshot := s.current()
+	endpoint, found := snapshot.endpoint(name)
+	if snapshot.at.IsZero() || !found {
+		// shields.io only shows the error of a successful response.
+		writeEndpoint(w, http.StatusOK, models.ShieldsEndpoint{Label: name, Message: "unavailable", Color: "lightgrey", IsError: true})
+		return
+	}
+	endpoint.CacheSeconds = int(s.ttl.Seconds())
+	writeEndpoint(w, http.StatusOK, endpoint)
+}
+
+// known reports whether name is a badge rule id or a metric.
+func (s *BadgeServer) known(name string) bool {
+	for _, rule := range s.rules {
+		if rule.ID == name {
+			return true
+		}
+	}
+	_, ok := MetricValue(models.ReportMetrics{}, strings.ReplaceAll(name, "-", "_"))
+	return ok
+}
+
+// endpoint looks name up as a badge rule id, then as a metric.
+func (s badgeSnapshot) endpoint(name string) (models.ShieldsEndpoint, bool) {
+	for _, result := range s.results {
+		if result.Rule.ID != name {
+			continue
+		}
+		rule := result.Rule
+		if !result.Earned() {
+			tier := ruleTiers(rule)[0]
+			message := formatMetric(result.Value) + "/" + formatMetric(tier.Threshold)
+			return models.ShieldsEndpoint{Label: iconLabel(rule), Message: message, Color: "lightgrey"}, true
+		}
+		return models.ShieldsEndpoint{Label: iconLabel(rule), Message: badgeMessage(rule, result.Tier), Color: badgeColor(rule, result.Tier)}, true
+	}
+
+	metric := strings.ReplaceAll(name, "-", "_")
+	value, ok := MetricValue(s.metrics, metric)
+	if !ok {
+		return models.ShieldsEndpoint{}, false
+	}
+	return models.ShieldsEndpoint{Label: metricUnit(metric), Message: formatMetric(math.Round(value*10) / 10), Color: "blue"}, true
+}
+
+func writeEndpoint(w http.ResponseWriter, status int, endpoint models.ShieldsEndpoint) {
+	endpoint.SchemaVersion = 1
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(endpoint); err != nil {
+		log.Println("⚠️ Failed to write badge response:", err)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pm/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column sets are part of the CSV contract: append new columns at the end only.
+var (
+	prColumns      = []string{"repo", "number", "title", "state", "author", "created_at", "merged_at", "hours_to_merge", "additions", "deletions", "changed_files", "url"}
+	commitColumns  = []string{"repo", "sha", "date", "author", "message", "url"}
+	repoColumns    = []string{"repo", "url", "updated_at", "languages", "prs_merged", "commits", "additions", "deletions", "changed_files", "stars", "forks", "watchers", "issues_fixed", "reviews"}
+	summaryColumns = []string{"metric", "value"}
+)
+
+// ExportCSV writes prs.csv, commits.csv, repos.csv and summary.csv into a
+// dated directory under baseDir and returns the directory it wrote to.
+func ExportCSV(data models.ReportData, baseDir string) (string, error) {
+	dir := filepath.Join(baseDir, data.GeneratedAt.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create export dir: %w", err)
+	}
+
+	files := []struct {
+		name    string
+		columns []string
+		rows    [][]string
+	}{
+		{"prs.csv", prColumns, prRows(data)},
+		{"commits.csv", commitColumns, commitRows(data)},
+		{"repos.csv", repoColumns, repoRows(data)},
+		{"summary.csv", summaryColumns, summaryRows(data)},
+	}
+
+	for _, f := range files {
+		if err := writeCSV(filepath.Join(dir, f.name), f.columns, f.rows); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func writeCSV(path string, columns []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func prRows(data models.ReportData) [][]string {
+	var rows [][]string
+	for _, activity := range data.Repos {
+		for _, pr := range activity.PullRequests {
+			rows = append(rows, []string{
+				activity.Repo.FullName,
+				strconv.Itoa(pr.Number),
+				pr.Title,
+				pr.State,
+				pr.User.Login,
+				pr.CreatedAt,
+				pr.MergedAt,
+				hoursToMerge(pr),
+				strconv.Itoa(pr.Additions),
+				strconv.Itoa(pr.Deletions),
+				strconv.Itoa(pr.ChangedFiles),
+				pr.HTMLURL,
+			})
+		}
+	}
+	return rows
+}
+
+func commitRows(data models.ReportData) [][]string {
+	var rows [][]string
+	for _, activity := range data.Repos {
+		for _, c := range activity.Commits {
+			message, _, _ := strings.Cut(c.Commit.Message, "\n")
+			rows = append(rows, []string{
+				activity.Repo.FullName,
+				c.SHA,
+				c.Commit.Author.Date,
+				c.Commit.Author.Name,
+				message,
+				c.HTMLURL,
+			})
+		}
+	}
+	return rows
+}
+
+func repoRows(data models.ReportData) [][]string {
+	var rows [][]string
+	for _, activity := range data.Repos {
+		additions, deletions, changedFiles := 0, 0, 0
+		for _, pr := range activity.PullRequests {
+			additions += pr.Additions
+			deletions += pr.Deletions
+			changedFiles += pr.ChangedFiles
+		}
+
+		var langs []string
+		for lang := range activity.Languages {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+
+		repo := activity.Repo
+		rows = append(rows, []string{
+			repo.FullName,
+			repo.HTMLURL,
+			repo.UpdatedAt,
+			strings.Join(langs, ";"),
+			strconv.Itoa(len(activity.PullRequests)),
+			strconv.Itoa(len(activity.Commits)),
+			strconv.Itoa(additions),
+			strconv.Itoa(deletions),
+			strconv.Itoa(changedFiles),
+			strconv.Itoa(repo.StargazersCount),
+			strconv.Itoa(repo.ForksCount),
+			strconv.Itoa(repo.WatchersCount),
+			strconv.Itoa(repo.IssueFixCount),
+			strconv.Itoa(repo.ReviewCount),
+		})
+	}
+	return rows
+}
+
+func summaryRows(data models.ReportData) [][]string {
+	metrics := ComputeMetrics(data)
+
+	avgHours := ""
+	if metrics.AvgTimeToMerge > 0 {
+		avgHours = strconv.FormatFloat(metrics.AvgTimeToMerge.Hours(), 'f', 2, 64)
+	}
+
+	return [][]string{
+		{"username", data.Username},
+		{"since", data.Since.Format("2006-01-02")},
+		{"generated_at", data.GeneratedAt.Format(time.RFC3339)},
+		{"repositories", strconv.Itoa(metrics.Repositories)},
+		{"prs_merged", strconv.Itoa(metrics.PRsMerged)},
+		{"commits", strconv.Itoa(metrics.Commits)},
+		{"additions", strconv.Itoa(metrics.Additions)},
+		{"deletions", strconv.Itoa(metrics.Deletions)},
+		{"avg_hours_to_merge", avgHours},
+		{"issues_fixed", strconv.Itoa(metrics.IssuesFixed)},
+		{"reviews", strconv.Itoa(metrics.Reviews)},
+		{"stars", strconv.Itoa(metrics.Stars)},
+		{"forks", strconv.Itoa(metrics.Forks)},
+	}
+}
+
+// jsonReport is the JSON export. Like the CSV columns, its fields are a
+// contract: add new ones, never rename them.
+type jsonReport struct {
+	Username    string      `json:"username"`
+	Since       string      `json:"since"`
+	GeneratedAt string      `json:"generated_at"`
+	Metrics     jsonMetrics `json:"metrics"`
+	Repos       []jsonRepo  `json:"repos"`
+}
+
+type jsonMetrics struct {
+	Repositories       int            `json:"repositories"`
+	PRsMerged          int            `json:"prs_merged"`
+	Commits            int            `json:"commits"`
+	Additions          int            `json:"additions"`
+	Deletions          int            `json:"deletions"`
+	ChangedFiles       int            `json:"changed_files"`
+	IssuesFixed        int            `json:"issues_fixed"`
+	Reviews            int            `json:"reviews"`
+	Stars              int            `json:"stars"`
+	Forks              int            `json:"forks"`
+	AvgHoursToMerge    float64        `json:"avg_hours_to_merge"`
+	MedianHoursToMerge float64        `json:"median_hours_to_merge"`
+	Languages          map[string]int `json:"languages"`
+}
+
+type jsonRepo struct {
+	Repo         string               `json:"repo"`
+	URL          string               `json:"url"`
+	Languages    map[string]int       `json:"languages"`
+	PullRequests []models.PullRequest `json:"pull_requests"`
+	Commits      []models.Commit      `json:"commits"`
+}
+
+// ExportJSON returns the report data and its metrics as indented JSON.
+func ExportJSON(data models.ReportData) ([]byte, error) {
+	metrics := ComputeMetrics(data)
+	report := jsonReport{
+		Username:    data.Username,
+		Since:       data.Since.Format("2006-01-02"),
+		GeneratedAt: data.GeneratedAt.Format(time.RFC3339),
+		Metrics: jsonMetrics{
+			Repositories:       metrics.Repositories,
+			PRsMerged:          metrics.PRsMerged,
+			Commits:            metrics.Commits,
+			Additions:          metrics.Additions,
+			Deletions:          metrics.Deletions,
+			ChangedFiles:       metrics.ChangedFiles,
+			IssuesFixed:        metrics.IssuesFixed,
+			Reviews:            metrics.Reviews,
+			Stars:              metrics.Stars,
+			Forks:              metrics.Forks,
+			AvgHoursToMerge:    metrics.AvgTimeToMerge.Hours(),
+			MedianHoursToMerge: metrics.MedianTimeToMerge.Hours(),
+			Languages:          metrics.Languages,
+		},
+		Repos: []jsonRepo{},
+	}
+	for _, activity := range data.Repos {
+		report.Repos = append(report.Repos, jsonRepo{
+			Repo:         activity.Repo.FullName,
+			URL:          activity.Repo.HTMLURL,
+			Languages:    activity.Languages,
+			PullRequests: activity.PullRequests,
+			Commits:      activity.Commits,
+		})
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	return append(content, '\n'), nil
+}
+
+func hoursToMerge(pr models.PullRequest) string {
+	d, ok := timeToMerge(pr)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	githubclient "pm/client"
+	"pm/models"
+	"slices"
+	"strings"
+)
+
+// APIRepo reads files of a GitHub repository and commits changes to them
+// through the GitHub API, so no local clone is needed. Files are read at the commit the branch pointed at on the first
+// read, and a commit only lands if the branch has not moved since.
+type APIRepo struct {
+	Owner   string
+	Repo    string
+	Branch  string
+	Message string
+	Author  *models.CommitIdentity
+
+	token string
+	// head is the commit files are read at and committed on top of.
+	head string
+}
+
+// NewAPIRepo opens fullName ("owner/repo") on branch.
+func NewAPIRepo(token, fullName, branch string) (*APIRepo, error) {
+	owner, repo, err := splitRepoName(fullName)
+	if err != nil {
+		return nil, err
+	}
+	return &APIRepo{Owner: owner, Repo: repo, Branch: branch, token: token}, nil
+}
+
+func (r *APIRepo) FullName() string {
+	return r.Owner + "/" + r.Repo
+}
+
+// resolveHead returns the commit the branch pointed at when first asked.
+func (r *APIRepo) resolveHead() (string, error) {
+	if r.head != "" {
+		return r.head, nil
+	}
+	head, err := githubclient.GetBranchSHA(r.token, r.Owner, r.Repo, r.Branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s of %s: %w", r.Branch, r.FullName(), err)
+	}
+	r.head = head
+	return head, nil
+}
+
+// ReadFile returns the content of path in the repo. A missing file is
+// reported as os.ErrNotExist.
+func (r *APIRepo) ReadFile(path string) ([]byte, error) {
+	head, err := r.resolveHead()
+	if err != nil {
+		return nil, err
+	}
+	content, err := githubclient.GetRepoContent(r.token, r.Owner, r.Repo, path, head)
+	if errors.Is(err, githubclient.ErrNotFound) {
+		return nil, fmt.Errorf("%s in %s: %w", path, r.FullName(), os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content.Encoding != "base64" {
+		return nil, fmt.Errorf("%s in %s: unsupported encoding %q", path, r.FullName(), content.Encoding)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return decoded, nil
+}
+
+// Commit writes files, keyed by their path in the repo, to the branch in a
+// single commit on top of the one they were read at. It fails with
+// client.ErrConflict when the branch moved since, leaving it untouched.
+func (r *APIRepo) Commit(files map[string][]byte) error {
+	head, err := r.resolveHead()
+	if err != nil {
+		return err
+	}
+	baseTree, err := githubclient.GetCommitTree(r.token, r.Owner, r.Repo, head)
+	if err != nil {
+		return err
+	}
+	var entries []models.TreeEntry
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		entries = append(entries, models.TreeEntry{Path: path, Mode: "100644", Type: "blob", Content: string(files[path])})
+	}
+	tree, err := githubclient.CreateTree(r.token, r.Owner, r.Repo, baseTree, entries)
+	if err != nil {
+		return err
+	}
+	commit, err := githubclient.CreateCommit(r.token, r.Owner, r.Repo, models.NewCommit{
+		Message: r.Message,
+		Tree:    tree,
+		Parents: []string{head},
+		Author:  r.Author,
+	})
+	if err != nil {
+		return err
+	}
+	if err := githubclient.UpdateBranch(r.token, r.Owner, r.Repo, r.Branch, commit); err != nil {
+		return fmt.Errorf("failed to update %s of %s: %w", r.Branch, r.FullName(), err)
+	}
+	r.head = commit
+	return nil
+}
+
+// SwitchBranch points later reads and commits at branch, from its current
+// head.
+func (r *APIRepo) SwitchBranch(branch string) {
+	r.Branch = branch
+	r.head = ""
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"pm/models"
+	"pm/svg"
+	"time"
+)
+
+const ledgerVersion = 1
+
+// ParseLedger decodes a ledger read from path; empty content is an empty ledger.
+func ParseLedger(content []byte, path string) (models.BadgeLedger, error) {
+	ledger := models.BadgeLedger{Version: ledgerVersion}
+	if len(content) == 0 {
+		return ledger, nil
+	}
+	if err := json.Unmarshal(content, &ledger); err != nil {
+		return ledger, fmt.Errorf("failed to parse badge ledger %s: %w", path, err)
+	}
+	if ledger.Version > ledgerVersion {
+		return ledger, fmt.Errorf("badge ledger %s has version %d, this pm supports %d", path, ledger.Version, ledgerVersion)
+	}
+	return ledger, nil
+}
+
+func MarshalLedger(ledger models.BadgeLedger) string {
+	ledger.Version = ledgerVersion
+	content, _ := json.MarshalIndent(ledger, "", "  ")
+	return string(content) + "\n"
+}
+
+// ledgerIndex returns the index of the latest entry recorded for a badge id,
+// or -1.
+func ledgerIndex(ledger models.BadgeLedger, id string) int {
+	for i := len(ledger.Entries) - 1; i >= 0; i-- {
+		if ledger.Entries[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ledgerEntry returns the latest entry recorded for a badge id.
+func ledgerEntry(ledger models.BadgeLedger, id string) (models.BadgeLedgerEntry, bool) {
+	if i := ledgerIndex(ledger, id); i >= 0 {
+		return ledger.Entries[i], true
+	}
+	return models.BadgeLedgerEntry{}, false
+}
+
+// ledgerTier returns the tier index of rule held in the ledger, or -1 when
+// it was never earned or has expired.
+func ledgerTier(ledger models.BadgeLedger, rule models.BadgeRule) int {
+	entry, ok := ledgerEntry(ledger, rule.ID)
+	if !ok || entry.ExpiredAt != nil {
+		return -1
+	}
+	if len(rule.Tiers) == 0 {
+		return 0
+	}
+	for tier, t := range rule.Tiers {
+		if t.Name == entry.Tier {
+			return tier
+		}
+	}
+	return -1
+}
+
+func recordBadge(ledger *models.BadgeLedger, rule models.BadgeRule, tier int, value float64, evidence string, earnedAt time.Time) {
+	entry := models.BadgeLedgerEntry{ID: rule.ID, EarnedAt: earnedAt.UTC(), Value: value, Evidence: evidence}
+	if len(rule.Tiers) > 0 {
+		entry.Tier = rule.Tiers[tier].Name
+	}
+	ledger.Entries = append(ledger.Entries, entry)
+}
+
+// expireBadge marks the ledger's badge for result's rule expired when the rule
+// declares Expires, no longer holds at the recorded tier, and the badge was
+// earned before the Expires window started. It returns the expired tier, or -1.
+func expireBadge(ledger *models.BadgeLedger, result BadgeResult, now time.Time) int {
+	rule := result.Rule
+	tier := ledgerTier(*ledger, rule)
+	if rule.Expires == "" || tier < 0 || result.Tier >= tier {
+		return -1
+	}
+	start, err := WindowStart(rule.Expires, now)
+	if err != nil {
+		return -1
+	}
+	entry := &ledger.Entries[ledgerIndex(*ledger, rule.ID)]
+	if !entry.EarnedAt.Before(start) {
+		return -1
+	}
+	expiredAt := now.UTC()
+	entry.ExpiredAt = &expiredAt
+	return tier
+}
+
+// LedgerBadges renders the README markdown for every badge the ledger holds.
+func LedgerBadges(ledger models.BadgeLedger, rules []models.BadgeRule, renderer string) []string {
+	var badges []string
+	for _, rule := range rules {
+		if tier := ledgerTier(ledger, rule); tier >= 0 {
+			badges = append(badges, BadgeImage(rule, tier, renderer))
+		}
+	}
+	return badges
+}
+
+// LedgerBadgeSVGs renders the SVG file for every badge the ledger holds, keyed
+// by BadgeFile.
+func LedgerBadgeSVGs(ledger models.BadgeLedger, rules []models.BadgeRule, style svg.Style) map[string]string {
+	files := map[string]string{}
+	for _, rule := range rules {
+		if tier := ledgerTier(ledger, rule); tier >= 0 {
+			files[BadgeFile(rule)] = BadgeSVG(rule, tier, style)
+		}
+	}
+	return files
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"pm/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func ComputeMetrics(data models.ReportData) models.ReportMetrics {
+	metrics := models.ReportMetrics{
+		Repositories: len(data.Repos),
+		Reviews:      data.Reviews,
+		Languages:    map[string]int{},
+	}
+
+	var mergeTimes []time.Duration
+	for _, activity := range data.Repos {
+		metrics.Commits += len(activity.Commits)
+		metrics.IssuesFixed += activity.Repo.IssueFixCount
+		metrics.Reviews += activity.Repo.ReviewCount
+		metrics.Stars += activity.Repo.StargazersCount
+		metrics.Forks += activity.Repo.ForksCount
+		for lang, bytes := range activity.Languages {
+			metrics.Languages[lang] += bytes
+		}
+
+		for _, pr := range activity.PullRequests {
+			metrics.PRsMerged++
+			metrics.Additions += pr.Additions
+			metrics.Deletions += pr.Deletions
+			metrics.ChangedFiles += pr.ChangedFiles
+			if d, ok := timeToMerge(pr); ok {
+				mergeTimes = append(mergeTimes, d)
+			}
+		}
+	}
+
+	if len(mergeTimes) > 0 {
+		var total time.Duration
+		for _, d := range mergeTimes {
+			total += d
+		}
+		metrics.AvgTimeToMerge = total / time.Duration(len(mergeTimes))
+
+		sort.Slice(mergeTimes, func(i, j int) bool { return mergeTimes[i] < mergeTimes[j] })
+		mid := len(mergeTimes) / 2
+		if len(mergeTimes)%2 == 0 {
+			metrics.MedianTimeToMerge = (mergeTimes[mid-1] + mergeTimes[mid]) / 2
+		} else {
+			metrics.MedianTimeToMerge = mergeTimes[mid]
+		}
+	}
+
+	return metrics
+}
+
+func timeToMerge(pr models.PullRequest) (time.Duration, bool) {
+	createdAt, err1 := time.Parse(time.RFC3339, pr.CreatedAt)
+	mergedAt, err2 := time.Parse(time.RFC3339, pr.MergedAt)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return mergedAt.Sub(createdAt), true
+}
+
+// MetricNames lists the metrics addressable by name, e.g. from badge rules.
+var MetricNames = []string{
+	"repositories", "prs_merged", "commits", "additions", "deletions", "changed_files",
+	"issues_fixed", "reviews", "stars", "forks", "languages", "avg_hours_to_merge",
+	"median_hours_to_merge",
+}
+
+var metricLabels = map[string]string{
+	"repositories":          "Repositories",
+	"prs_merged":            "PRs merged",
+	"commits":               "Commits",
+	"additions":             "Additions",
+	"deletions":             "Deletions",
+	"changed_files":         "Changed files",
+	"issues_fixed":          "Issues fixed",
+	"reviews":               "Reviews",
+	"stars":                 "Stars",
+	"forks":                 "Forks",
+	"languages":             "Languages",
+	"avg_hours_to_merge":    "Average time to merge",
+	"median_hours_to_merge": "Median time to merge",
+}
+
+// MetricLabel returns the display name of a metric.
+func MetricLabel(name string) string {
+	if label, ok := metricLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// FormatMetricValue formats a metric's value: whole numbers without
+// decimals, others with one, and hours to merge with an "h".
+func FormatMetricValue(name string, v float64) string {
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	if v == math.Trunc(v) {
+		s = strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	if strings.HasSuffix(name, "_hours_to_merge") {
+		s += "h"
+	}
+	return s
+}
+
+// MetricValue looks up a metric by name.
+func MetricValue(metrics models.ReportMetrics, name string) (float64, bool) {
+	switch name {
+	case "repositories":
+		return float64(metrics.Repositories), true
+	case "prs_merged":
+		return float64(metrics.PRsMerged), true
+	case "commits":
+		return float64(metrics.Commits), true
+	case "additions":
+		return float64(metrics.Additions), true
+	case "deletions":
+		return float64(metrics.Deletions), true
+	case "changed_files":
+		return float64(metrics.ChangedFiles), true
+	case "issues_fixed":
+		return float64(metrics.IssuesFixed), true
+	case "reviews":
+		return float64(metrics.Reviews), true
+	case "stars":
+		return float64(metrics.Stars), true
+	case "forks":
+		return float64(metrics.Forks), true
+	case "languages":
+		return float64(len(metrics.Languages)), true
+	case "avg_hours_to_merge":
+		return metrics.AvgTimeToMerge.Hours(), true
+	case "median_hours_to_merge":
+		return metrics.MedianTimeToMerge.Hours(), true
+	}
+	return 0, false
+}
+
+// WindowStart resolves a metrics window: "all", a period name from Periods,
+// or a number of days such as "30d".
+func WindowStart(window string, now time.Time) (time.Time, error) {
+	if window == "all" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid window %q", window)
+		}
+		return now.AddDate(0, 0, -n), nil
+	}
+	return PeriodStart(window, now)
+}
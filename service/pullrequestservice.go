@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	githubclient "pm/client"
+	"pm/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BadgeBranch is the branch badge updates are committed to in pull request
+// mode, one per day.
+func BadgeBranch(now time.Time) string {
+	return "pm/badges-" + now.Format("2006-01-02")
+}
+
+func splitRepoName(fullName string) (string, string, error) {
+	owner, repo, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid repository %q: expected owner/name", fullName)
+	}
+	return owner, repo, nil
+}
+
+// EnsureBranch creates branch from the head of base unless it already exists.
+func EnsureBranch(token, fullName, branch, base string) error {
+	owner, repo, err := splitRepoName(fullName)
+	if err != nil {
+		return err
+	}
+	if _, err := githubclient.GetBranchSHA(token, owner, repo, branch); err == nil {
+		return nil
+	} else if !errors.Is(err, githubclient.ErrNotFound) {
+		return err
+	}
+	sha, err := githubclient.GetBranchSHA(token, owner, repo, base)
+	if err != nil {
+		return err
+	}
+	return githubclient.CreateBranch(token, owner, repo, branch, sha)
+}
+
+// OpenPullRequest opens a pull request from branch into base, or updates the
+// title and body of the one already open. It reports whether it was created.
+func OpenPullRequest(token, fullName, branch, base, title, body string) (models.PullRequest, bool, error) {
+	owner, repo, err := splitRepoName(fullName)
+	if err != nil {
+		return models.PullRequest{}, false, err
+	}
+	pr := models.NewPullRequest{Title: title, Head: branch, Base: base, Body: body}
+
+	existing, found, err := githubclient.FindPullRequest(token, owner, repo, owner+":"+branch, base)
+	if err != nil {
+		return existing, false, err
+	}
+	if found {
+		updated, err := githubclient.UpdatePullRequest(token, owner, repo, existing.Number, models.NewPullRequest{Title: title, Body: body})
+		return updated, false, err
+	}
+	created, err := githubclient.CreatePullRequest(token, owner, repo, pr)
+	return created, true, err
+}
+
+// BadgePullRequestBody lists the newly earned badges with the value that
+// earned them and the evidence, and the expired ones, for a pull request
+// description.
+func BadgePullRequestBody(results []BadgeResult, newBadges, expired []string) string {
+	var b strings.Builder
+	if len(expired) > 0 {
+		b.WriteString("🗑 Badges expired:\n\n")
+		for _, title := range expired {
+			fmt.Fprintf(&b, "- **%s**\n", title)
+		}
+		b.WriteString("\n")
+	}
+	if len(newBadges) == 0 {
+		b.WriteString("Refreshes the profile README generated by pm. No new badges were earned.\n")
+		return b.String()
+	}
+
+	b.WriteString("🏅 Badges earned:\n\n")
+	for _, result := range results {
+		if !result.Earned() || !slices.Contains(newBadges, badgeTitle(result.Rule, result.Tier)) {
+			continue
+		}
+		threshold := ruleTiers(result.Rule)[result.Tier].Threshold
+		fmt.Fprintf(&b, "- **%s**: %s %s (needs %s %s)", badgeTitle(result.Rule, result.Tier), formatMetric(result.Value), metricUnit(result.Rule.Metric), ruleComparator(result.Rule), formatMetric(threshold))
+		if result.Evidence != "" {
+			fmt.Fprintf(&b, ", reached with %s", result.Evidence)
+		}
+		b.WriteString("\n")
+	}
+	for _, title := range newBadges {
+		if !resultsHaveTitle(results, title) {
+			fmt.Fprintf(&b, "- **%s**: recorded in the ledger, missing from the README\n", title)
+		}
+	}
+	return b.String()
+}
+
+func resultsHaveTitle(results []BadgeResult, title string) bool {
+	for _, result := range results {
+		if result.Earned() && badgeTitle(result.Rule, result.Tier) == title {
+			return true
+		}
+	}
+	return false
+}
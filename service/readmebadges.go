@@ -0,0 +1,247 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"pm/models"
+	"pm/utils"
+	"strings"
+)
+
+// badgeIDAttr and badgeTierAttr let hand-written <img> tags declare which pm
+// badge they show.
+const (
+	badgeIDAttr   = "data-pm-badge"
+	badgeTierAttr = "data-pm-tier"
+)
+
+// ReadmeBadge is an image in the README identified as a pm badge. Tier is -1
+// when the image does not tell which tier it shows.
+type ReadmeBadge struct {
+	utils.Image
+	ID   string
+	Rule models.BadgeRule
+	Tier int
+}
+
+// ReadmeScan sorts the badges in a README into pm's own, foreign ones, and
+// pm badges that need cleaning up.
+type ReadmeScan struct {
+	Managed []ReadmeBadge
+	Foreign []utils.Image
+	// Stale badges show a tier the ledger does not hold or belong to a rule
+	// that no longer exists.
+	Stale []ReadmeBadge
+	// Duplicates repeat a badge already shown earlier in the README.
+	Duplicates []ReadmeBadge
+}
+
+// ScanReadmeBadges parses every image in content and classifies it against
+// rules and the ledger.
+func ScanReadmeBadges(content string, rules []models.BadgeRule, ledger models.BadgeLedger) ReadmeScan {
+	var scan ReadmeScan
+	blockStart, blockEnd := badgeBlockLines(content)
+	seen := map[string]bool{}
+
+	for _, img := range utils.ParseImages(content) {
+		badge, ok := identifyBadge(img, rules)
+		if !ok {
+			if isBadgeImage(img) || (img.Line > blockStart && img.Line < blockEnd) {
+				scan.Foreign = append(scan.Foreign, img)
+			}
+			continue
+		}
+
+		scan.Managed = append(scan.Managed, badge)
+		switch {
+		case seen[badge.ID]:
+			scan.Duplicates = append(scan.Duplicates, badge)
+		case badge.Rule.ID == "" || badge.Tier != ledgerTier(ledger, badge.Rule):
+			scan.Stale = append(scan.Stale, badge)
+		}
+		seen[badge.ID] = true
+	}
+	return scan
+}
+
+// highestTier returns the highest tier of rule shown in the README, or -1.
+func (s ReadmeScan) highestTier(rule models.BadgeRule) int {
+	tier := -1
+	for _, badge := range s.Managed {
+		if badge.ID == rule.ID {
+			tier = max(tier, badge.Tier)
+		}
+	}
+	return tier
+}
+
+// identifyBadge matches img to a rule by, in order, a data-pm-badge attribute,
+// the self-hosted SVG path, the shields.io label, or the alt text.
+func identifyBadge(img utils.Image, rules []models.BadgeRule) (ReadmeBadge, bool) {
+	badge := ReadmeBadge{Image: img, Tier: -1}
+
+	if id := img.Attrs[badgeIDAttr]; id != "" {
+		badge.ID = id
+		for _, rule := range rules {
+			if rule.ID == id {
+				badge.Rule = rule
+				badge.Tier = tierByName(rule, img.Attrs[badgeTierAttr])
+				if badge.Tier < 0 {
+					badge.Tier = imageTier(rule, img.Alt)
+				}
+			}
+		}
+		return badge, true
+	}
+
+	src := strings.TrimPrefix(path.Clean(img.Src), "./")
+	label, message, isShields := parseShieldsURL(img.Src)
+	for _, rule := range rules {
+		switch {
+		case src == BadgeFile(rule):
+			badge.Tier = imageTier(rule, img.Alt)
+		case isShields && (label == rule.Label || strings.HasSuffix(label, " "+rule.Label)):
+			badge.Tier = tierByMessage(rule, message)
+		case !isShields && tierByAlt(rule, img.Alt) >= 0:
+			badge.Tier = tierByAlt(rule, img.Alt)
+		default:
+			continue
+		}
+		badge.ID, badge.Rule = rule.ID, rule
+		return badge, true
+	}
+	return badge, false
+}
+
+func tierByName(rule models.BadgeRule, name string) int {
+	for tier, t := range rule.Tiers {
+		if strings.EqualFold(t.Name, name) {
+			return tier
+		}
+	}
+	return -1
+}
+
+// tierByAlt reads the tier from alt text rendered by badgeAlt.
+func tierByAlt(rule models.BadgeRule, alt string) int {
+	for tier := range ruleTiers(rule) {
+		if alt == badgeAlt(rule, tier) {
+			return tier
+		}
+	}
+	return -1
+}
+
+// imageTier is the tier of an image already known to show rule. Untiered
+// rules only have tier 0, whatever the alt text.
+func imageTier(rule models.BadgeRule, alt string) int {
+	if len(rule.Tiers) == 0 {
+		return 0
+	}
+	return tierByAlt(rule, alt)
+}
+
+func tierByMessage(rule models.BadgeRule, message string) int {
+	for tier := range ruleTiers(rule) {
+		if message == badgeMessage(rule, tier) {
+			return tier
+		}
+	}
+	return -1
+}
+
+// parseShieldsURL splits an img.shields.io static badge URL into its label and
+// message.
+func parseShieldsURL(src string) (label, message string, ok bool) {
+	u, err := url.Parse(src)
+	if err != nil || !strings.HasSuffix(u.Host, "shields.io") || !strings.HasPrefix(u.Path, "/badge/") {
+		return "", "", false
+	}
+
+	var parts []string
+	var part strings.Builder
+	p := strings.TrimPrefix(u.Path, "/badge/")
+	for i := 0; i < len(p); i++ {
+		switch {
+		case p[i] == '-' && i+1 < len(p) && p[i+1] == '-':
+			part.WriteByte('-')
+			i++
+		case p[i] == '-':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(p[i])
+		}
+	}
+	parts = append(parts, part.String())
+	if len(parts) < 2 {
+		return "", "", false
+	}
+
+	unescape := strings.NewReplacer("__", "_", "_", " ")
+	return unescape.Replace(parts[0]), unescape.Replace(parts[1]), true
+}
+
+// isBadgeImage reports whether img looks like a badge from a common badge
+// service.
+func isBadgeImage(img utils.Image) bool {
+	u, err := url.Parse(img.Src)
+	if err != nil {
+		return false
+	}
+	for _, host := range []string{"shields.io", "badgen.net", "badge.fury.io", "codecov.io", "github.com"} {
+		if strings.HasSuffix(u.Host, host) && (host != "github.com" || strings.HasSuffix(u.Path, "/badge.svg")) {
+			return true
+		}
+	}
+	return false
+}
+
+// badgeBlockLines returns the line numbers of the badge block markers, or
+// zeros when there is no block.
+func badgeBlockLines(content string) (int, int) {
+	start := strings.Index(content, utils.BadgeBlockStart)
+	if start < 0 {
+		return 0, 0
+	}
+	end := strings.Index(content[start:], utils.BadgeBlockEnd)
+	if end < 0 {
+		return 0, 0
+	}
+	return strings.Count(content[:start], "\n") + 1, strings.Count(content[:start+end], "\n") + 1
+}
+
+// managedLine reports whether line holds nothing but pm badges.
+func managedLine(line string, rules []models.BadgeRule) bool {
+	images := utils.ParseImages(line)
+	for _, img := range images {
+		if _, ok := identifyBadge(img, rules); !ok {
+			return false
+		}
+		line = strings.Replace(line, img.Raw, "", 1)
+	}
+	return len(images) > 0 && strings.TrimSpace(line) == ""
+}
+
+// FormatReadmeScan describes the badges that need cleaning up and the foreign
+// badges pm leaves alone, or returns "" when there are none.
+func FormatReadmeScan(scan ReadmeScan) string {
+	var b strings.Builder
+	if len(scan.Stale)+len(scan.Duplicates) > 0 {
+		b.WriteString("\n🧹 README cleanup (fixed on sync):\n")
+		for _, badge := range scan.Duplicates {
+			fmt.Fprintf(&b, "- Duplicate %s badge on line %d\n", badge.ID, badge.Line)
+		}
+		for _, badge := range scan.Stale {
+			fmt.Fprintf(&b, "- Stale %s badge on line %d\n", badge.ID, badge.Line)
+		}
+	}
+	if len(scan.Foreign) > 0 {
+		b.WriteString("\n🔖 Other badges (left untouched):\n")
+		for _, img := range scan.Foreign {
+			fmt.Fprintf(&b, "- %s on line %d\n", img.Src, img.Line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"pm/models"
+	"pm/utils"
+	"slices"
+	"testing"
+)
+
+func TestParseShieldsURL(t *testing.T) {
+	tests := []struct {
+		src            string
+		label, message string
+		ok             bool
+	}{
+		{"https://img.shields.io/badge/PRs-12-blue", "PRs", "12", true},
+		{"https://img.shields.io/badge/1st%20PR-achieved-green", "1st PR", "achieved", true},
+		{"https://img.shields.io/badge/%F0%9F%8F%86%20Merged-Gold-gold", "🏆 Merged", "Gold", true},
+		{"https://img.shields.io/badge/co--author-two__words_here-blue", "co-author", "two_words here", true},
+		{"https://img.shields.io/badge/label-message", "label", "message", true},
+		{"https://img.shields.io/badge/just-a-b-c", "just", "a", true},
+		{"https://img.shields.io/badge/alone", "", "", false},
+		{"https://img.shields.io/github/stars/x/y", "", "", false},
+		{"https://badgen.net/badge/PRs/12/blue", "", "", false},
+		{"badges/prs.svg", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			label, message, ok := parseShieldsURL(tt.src)
+			if label != tt.label || message != tt.message || ok != tt.ok {
+				t.Errorf("parseShieldsURL() = %q, %q, %v, want %q, %q, %v", label, message, ok, tt.label, tt.message, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIdentifyBadge(t *testing.T) {
+	rules := []models.BadgeRule{
+		{ID: "first-pr", Label: "1st PR", Icon: "🏆", Metric: "prs_merged", Threshold: 1},
+		{ID: "merged", Name: "Merger", Label: "Merged", Metric: "prs_merged", Tiers: []models.BadgeTier{
+			{Name: "Bronze", Threshold: 10},
+			{Name: "Silver", Threshold: 50},
+		}},
+	}
+	tests := []struct {
+		name string
+		img  string
+		id   string
+		tier int
+		ok   bool
+	}{
+		{"data attribute", `<img src="x.png" data-pm-badge="merged" data-pm-tier="silver">`, "merged", 1, true},
+		{"data attribute falls back to alt", `<img alt="Merger (Bronze)" src="x.png" data-pm-badge="merged">`, "merged", 0, true},
+		{"data attribute of an unknown rule", `<img src="x.png" data-pm-badge="gone">`, "gone", -1, true},
+		{"self-hosted svg", "![Merger (Silver)](./badges/merged.svg)", "merged", 1, true},
+		{"self-hosted svg of an untiered rule", "![anything](badges/first-pr.svg)", "first-pr", 0, true},
+		{"shields label with icon", "![x](https://img.shields.io/badge/%F0%9F%8F%86%201st%20PR-achieved-green)", "first-pr", 0, true},
+		{"legacy shields label without icon", "![x](https://img.shields.io/badge/1st%20PR-achieved-green)", "first-pr", 0, true},
+		{"legacy shields label with another icon", "![x](https://img.shields.io/badge/%E2%AD%90%201st%20PR-achieved-green)", "first-pr", 0, true},
+		{"shields tier by message", "![x](https://img.shields.io/badge/Merged-Silver-silver)", "merged", 1, true},
+		{"shields with an unknown message", "![x](https://img.shields.io/badge/Merged-Platinum-blue)", "merged", -1, true},
+		{"shields label only as a substring", "![x](https://img.shields.io/badge/Unmerged-Silver-blue)", "", -1, false},
+		{"alt text", "![Merger (Bronze)](https://example.com/b.png)", "merged", 0, true},
+		{"foreign image", "![CI](https://github.com/x/y/actions/workflows/ci.yml/badge.svg)", "", -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images := utils.ParseImages(tt.img)
+			if len(images) != 1 {
+				t.Fatalf("ParseImages(%q) found %d images", tt.img, len(images))
+			}
+			badge, ok := identifyBadge(images[0], rules)
+			if badge.ID != tt.id || badge.Tier != tt.tier || ok != tt.ok {
+				t.Errorf("identifyBadge() = %q, tier %d, %v, want %q, tier %d, %v", badge.ID, badge.Tier, ok, tt.id, tt.tier, tt.ok)
+			}
+		})
+	}
+}
+
+func TestScanReadmeBadges(t *testing.T) {
+	rules := []models.BadgeRule{
+		{ID: "first-pr", Label: "1st PR", Metric: "prs_merged", Threshold: 1},
+		{ID: "merged", Label: "Merged", Metric: "prs_merged", Tiers: []models.BadgeTier{
+			{Name: "Bronze", Threshold: 10},
+			{Name: "Silver", Threshold: 50},
+		}},
+	}
+	ledger := models.BadgeLedger{Entries: []models.BadgeLedgerEntry{
+		{ID: "first-pr"},
+		{ID: "merged", Tier: "Silver"},
+	}}
+	content := "# Me\n" +
+		"![CI](https://github.com/me/x/actions/workflows/ci.yml/badge.svg)\n" +
+		"![x](https://img.shields.io/badge/1st%20PR-achieved-green)\n" +
+		"![Merged (Bronze)](badges/merged.svg)\n" +
+		"![1st PR](badges/first-pr.svg)\n" +
+		"![photo](me.png)\n" +
+		"```\n![1st PR](badges/first-pr.svg)\n```\n"
+
+	scan := ScanReadmeBadges(content, rules, ledger)
+	lines := func(badges []ReadmeBadge) []int {
+		var out []int
+		for _, b := range badges {
+			out = append(out, b.Line)
+		}
+		return out
+	}
+	if got := lines(scan.Managed); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("Managed on lines %v, want [3 4 5]", got)
+	}
+	if got := lines(scan.Stale); !slices.Equal(got, []int{4}) {
+		t.Errorf("Stale on lines %v, want [4]", got)
+	}
+	if got := lines(scan.Duplicates); !slices.Equal(got, []int{5}) {
+		t.Errorf("Duplicates on lines %v, want [5]", got)
+	}
+	if len(scan.Foreign) != 1 || scan.Foreign[0].Line != 2 {
+		t.Errorf("Foreign = %+v, want the CI badge on line 2", scan.Foreign)
+	}
+}
@@ -9,315 +9,128 @@ import (
 	"time"
 )
 
-func generateRepoLevelMetrics(token, owner, repoName string, sinceDate time.Time) string {
-	var b strings.Builder
-
-	// Languages
-	languages, err := githubclient.GetRepoLanguages(token, owner, repoName)
-	if err != nil {
-		b.WriteString(fmt.Sprintf("   ⚠️ Error fetching languages: %v\n", err))
-	} else if len(languages) > 0 {
-		var langList []string
-		for lang := range languages {
-			langList = append(langList, lang)
+// LoadTemplateData gets the report data since since from source and, when
+// compare is set, compares it with the previous equivalent period.
+func LoadTemplateData(source ReportSource, title string, since time.Time, compare bool) (TemplateData, error) {
+	if !compare {
+		data, err := source.Get(since)
+		if err != nil {
+			return TemplateData{}, err
 		}
-		b.WriteString(fmt.Sprintf("   Languages: %s\n", strings.Join(langList, ", ")))
+		return NewTemplateData(title, data), nil
 	}
-
-	// Pull Requests
-	prs, err := githubclient.GetUserMergedPRs(token, owner, repoName, sinceDate)
+	current, previous, err := GetComparison(source, since)
 	if err != nil {
-		b.WriteString(fmt.Sprintf("   ⚠️ Error fetching PRs: %v\n", err))
-		return b.String()
+		return TemplateData{}, err
 	}
-
-	for _, pr := range prs {
-		b.WriteString(fmt.Sprintf("   🟢 PR: %s\n", pr.Title))
-		b.WriteString(fmt.Sprintf("     Description : %s\n", pr.Body))
-		b.WriteString(fmt.Sprintf("     📁 Files changed: %d\n", pr.ChangedFiles))
-		b.WriteString(fmt.Sprintf("     ✍️ Lines changed: +%d -%d\n", pr.Additions, pr.Deletions))
-	}
-
-	return b.String()
+	return NewComparisonTemplateData(title, current, previous), nil
 }
 
-func generateIssueEngagementMetrics(repos []models.GithubRepo) string {
-	issueCount := 0
-	for _, repo := range repos {
-		issueCount += repo.IssueFixCount
-	}
-	if issueCount == 0 {
-		return ""
-	}
-	return fmt.Sprintf("🐞 Issues Fixed: %d", issueCount)
-}
+func CollectReportData(token string, since time.Time) (models.ReportData, error) {
+	data := models.ReportData{Since: since, GeneratedAt: time.Now().In(since.Location())}
 
-func generateCollaborationMetrics(token string, repos []models.GithubRepo, since time.Time) string {
-	reviewCount := 0
-	for _, repo := range repos {
-		reviewCount += repo.ReviewCount
-	}
-	if reviewCount == 0 {
-		return ""
+	username, err := githubclient.GetGitHubUsername(token)
+	if err != nil {
+		return data, fmt.Errorf("could not retrieve GitHub username: %w", err)
 	}
-	return fmt.Sprintf("🔍 PRs Reviewed: %d", reviewCount)
-}
+	data.Username = username
 
-func generateCommitLevelMetrics(token string, repos []models.GithubRepo, sincetime time.Time) string {
-	username, err := githubclient.GetGitHubUsername(token)
+	repos, err := githubclient.GetUserRepos(token, since)
 	if err != nil {
-		log.Println("⚠️ Could not retrieve GitHub username:", err)
-		return ""
+		return data, fmt.Errorf("failed to fetch repos: %w", err)
 	}
 
-	commitCount := 0
 	for _, repo := range repos {
 		parts := strings.Split(repo.FullName, "/")
 		if len(parts) != 2 {
 			continue
 		}
 		owner, repoName := parts[0], parts[1]
+		activity := models.RepoActivity{Repo: repo}
 
-		count, err := githubclient.GetUserCommits(token, owner, repoName, username, sincetime)
-		if err == nil {
-			commitCount += count
+		langs, err := githubclient.GetRepoLanguages(token, owner, repoName)
+		if err != nil {
+			log.Printf("⚠️ Failed to fetch languages for %s: %v", repo.FullName, err)
+		} else {
+			activity.Languages = langs
 		}
-	}
-	if commitCount == 0 {
-		return ""
-	}
-	return fmt.Sprintf("🔢 Total Commits: %d", commitCount)
-}
-
-func generatePullRequestMetrics(token string, repos []models.GithubRepo, since time.Time) string {
-	totalPRs := 0
-	var totalMergeTime time.Duration
-	prCount := 0
 
-	for _, repo := range repos {
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner, repoName := parts[0], parts[1]
-		prs, err := githubclient.GetUserMergedPRs(token, owner, repoName, since)
+		// Both list every page: a comparison's previous period is the older
+		// half of the range, which a single page would cut off.
+		prs, err := githubclient.ListMergedPRsUpdatedSince(token, owner, repoName, since)
 		if err != nil {
-			continue
+			log.Printf("⚠️ Failed to fetch PRs for %s: %v", repo.FullName, err)
 		}
 		for _, pr := range prs {
-			totalPRs++
-			createdAt, err1 := time.Parse(time.RFC3339, pr.CreatedAt)
-			mergedAt, err2 := time.Parse(time.RFC3339, pr.MergedAt)
-			if err1 == nil && err2 == nil {
-				totalMergeTime += mergedAt.Sub(createdAt)
-				prCount++
+			if inPeriod(pr.MergedAt, since, data.GeneratedAt) {
+				activity.PullRequests = append(activity.PullRequests, pr)
 			}
 		}
-	}
-
-	if totalPRs == 0 {
-		return ""
-	}
-
-	avgMergeTime := totalMergeTime / time.Duration(prCount)
-	return fmt.Sprintf("🧮 Total Merged PRs: %d\n⏱ Average Time to Merge: %s", totalPRs, avgMergeTime.Round(time.Minute))
-}
 
-func CalculateTotalPRs(token string, since time.Time) (int, error) {
-	total := 0
-	repos, err := githubclient.GetUserRepos(token, since)
-	if err != nil {
-		log.Fatalf("Failed to fetch repos: %v", err)
-	}
-	
-	for _, repo := range repos {
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner := parts[0]
-		name := parts[1]
-
-		prs, err := githubclient.GetUserMergedPRs(token, owner, name, since)
+		commits, err := githubclient.ListCommitsSince(token, owner, repoName, username, since)
 		if err != nil {
-			log.Printf("⚠️ Failed to fetch PRs for %s: %v", repo.FullName, err)
-			continue
+			log.Printf("⚠️ Failed to fetch commits for %s: %v", repo.FullName, err)
+		} else {
+			activity.Commits = commits
 		}
 
-		total += len(prs)
-	}
-
-	return total, nil
-}
-
-func BuildSummary(token string, since time.Time) string {
-	username, err := githubclient.GetGitHubUsername(token)
-	if err != nil {
-		log.Println("⚠️ Could not retrieve GitHub username:", err)
-		return "No data available"
+		data.Repos = append(data.Repos, activity)
 	}
 
-	repos, err := githubclient.GetUserRepos(token, since)
+	reviewed, total, err := githubclient.GetUserReviewedPRs(token, username, since)
 	if err != nil {
-		log.Println("⚠️ Error fetching repos:", err)
-		return "No data available"
+		log.Printf("⚠️ Failed to fetch reviews: %v", err)
+	} else {
+		data.Reviews, data.ReviewedPRs = total, reviewed
 	}
 
-	totalPRs := 0
-	totalCommits := 0
-	totalIssues := 0
-	repoCount := len(repos)
-	stars := 0
-	forks := 0
-
-	for _, repo := range repos {
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner, repoName := parts[0], parts[1]
-
-		prs, err := githubclient.GetUserMergedPRs(token, owner, repoName, since)
-		if err == nil {
-			totalPRs += len(prs)
-		}
+	return data, nil
+}
 
-		commitCount, err := githubclient.GetUserCommits(token, owner, repoName, username, since)
-		if err == nil {
-			totalCommits += commitCount
-		}
+// ReportSource provides the report data for the period starting at since.
+type ReportSource interface {
+	Get(since time.Time) (models.ReportData, error)
+}
 
-		totalIssues += repo.IssueFixCount
-		stars += repo.StargazersCount
-		forks += repo.ForksCount
-	}
+// ReportDataCache collects report data at most once per start time, so badge
+// rules and the stats card sharing a window share one fetch.
+type ReportDataCache struct {
+	token string
+	data  map[time.Time]models.ReportData
+}
 
-	return fmt.Sprintf(
-		"📦 Repositories: %d\n🟢 PRs Merged: %d\n🔢 Commits: %d\n🐞 Issues Fixed: %d\n⭐ Stars: %d\n🍴 Forks: %d",
-		repoCount, totalPRs, totalCommits, totalIssues, stars, forks,
-	)
+func NewReportDataCache(token string) *ReportDataCache {
+	return &ReportDataCache{token: token, data: map[time.Time]models.ReportData{}}
 }
 
-func BuildDetailedReport(token string, since time.Time) string {
-	username, err := githubclient.GetGitHubUsername(token)
-	if err != nil {
-		log.Println("⚠️ Could not retrieve GitHub username:", err)
-		return "No data available"
+func (c *ReportDataCache) Get(since time.Time) (models.ReportData, error) {
+	if data, ok := c.data[since]; ok {
+		return data, nil
 	}
-
-	repos, err := githubclient.GetUserRepos(token, since)
+	data, err := CollectReportData(c.token, since)
 	if err != nil {
-		log.Println("⚠️ Error fetching repos for detailed report:", err)
-		return "No data available"
+		return data, err
 	}
-
-	var report strings.Builder
-	report.WriteString("📊 Developer Metrics Report\n\n")
-	report.WriteString("📁 Repositories Included:\n")
-
-	totalCommits := 0
-	totalPRs := 0
-	var totalMergeTime time.Duration
-	prCount := 0
-
-	for _, repo := range repos {
-		report.WriteString(fmt.Sprintf(" - %s\n", repo.FullName))
-
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner, repoName := parts[0], parts[1]
-
-		// Languages
-		langs, err := githubclient.GetRepoLanguages(token, owner, repoName)
-		if err == nil && len(langs) > 0 {
-			var langList []string
-			for lang := range langs {
-				langList = append(langList, lang)
-			}
-			report.WriteString(fmt.Sprintf("   Languages: %s\n", strings.Join(langList, ", ")))
-		}
-
-		// Pull Requests
-		prs, err := githubclient.GetUserMergedPRs(token, owner, repoName, since)
-		if err == nil {
-			for _, pr := range prs {
-				report.WriteString(fmt.Sprintf("   🟢 PR: %s\n", pr.Title))
-				report.WriteString(fmt.Sprintf("     Description : %s\n", pr.Body))
-				report.WriteString(fmt.Sprintf("     📁 Files changed: %d\n", pr.ChangedFiles))
-				report.WriteString(fmt.Sprintf("     ✍️ Lines changed: +%d -%d\n", pr.Additions, pr.Deletions))
-				totalPRs++
-				createdAt, err1 := time.Parse(time.RFC3339, pr.CreatedAt)
-				mergedAt, err2 := time.Parse(time.RFC3339, pr.MergedAt)
-				if err1 == nil && err2 == nil {
-					totalMergeTime += mergedAt.Sub(createdAt)
-					prCount++
-				}
-			}
-		}
-
-		// Commits
-		commitCount, err := githubclient.GetUserCommits(token, owner, repoName, username, since)
-		if err == nil {
-			totalCommits += commitCount
-		}
-	}
-
-	// Pull Request Metrics
-	report.WriteString("\n📊 Pull Request Metrics:\n")
-	report.WriteString(fmt.Sprintf("🧮 Total Merged PRs: %d\n", totalPRs))
-	if prCount > 0 {
-		report.WriteString(fmt.Sprintf("⏱ Average Time to Merge: %s\n", (totalMergeTime / time.Duration(prCount)).Round(time.Minute)))
-	}
-
-	// Commit-Level Metrics
-	report.WriteString("\n📈 Commit-Level Metrics:\n")
-	report.WriteString(fmt.Sprintf("🔢 Total Commits: %d\n", totalCommits))
-
-	// Issue Engagement Metrics
-	report.WriteString("\n📌 Issue Engagement Metrics:\n")
-	report.WriteString(generateIssueEngagementMetrics(repos) + "\n")
-
-	// Collaboration Metrics
-	report.WriteString("\n👥 Collaboration Metrics:\n")
-	report.WriteString(generateCollaborationMetrics(token, repos, since) + "\n")
-
-	return report.String()
+	c.data[since] = data
+	return data, nil
 }
 
-func GenerateFullMetricsReport(token string, since time.Time) string {
-	repos, err := githubclient.GetUserRepos(token, since)
-	if err != nil {
-		log.Println("⚠️ Error fetching repos:", err)
-		return "No data available"
-	}
-
-	var report strings.Builder
-	report.WriteString("📊 Full Developer Metrics Report\n\n")
-	report.WriteString(fmt.Sprintf("Repositories updated since %s:\n", since.Format("2006-01-02")))
-	for _, repo := range repos {
-		parts := strings.Split(repo.FullName, "/")
-		if len(parts) != 2 {
-			continue
-		}
-		owner, repoName := parts[0], parts[1]
-		report.WriteString(fmt.Sprintf(" - %s\n", repo.FullName))
-		report.WriteString(generateRepoLevelMetrics(token, owner, repoName, since))
-		report.WriteString("\n")
-	}
-
-	report.WriteString("\n📊 Pull Request Metrics:\n")
-	report.WriteString(generatePullRequestMetrics(token, repos, since) + "\n")
-
-	report.WriteString("\n📈 Commit-Level Metrics:\n")
-	report.WriteString(generateCommitLevelMetrics(token, repos, since) + "\n")
-
-	report.WriteString("\n📌 Issue Engagement Metrics:\n")
-	report.WriteString(generateIssueEngagementMetrics(repos) + "\n")
-
-	report.WriteString("\n👥 Collaboration Metrics:\n")
-	report.WriteString(generateCollaborationMetrics(token, repos, since) + "\n")
-
-	return report.String()
+// Periods lists the report periods understood by PeriodStart, shortest first.
+var Periods = []string{"daily", "weekly", "monthly", "6-month", "yearly"}
+
+// PeriodStart returns the start of the named period ending at now.
+func PeriodStart(period string, now time.Time) (time.Time, error) {
+	switch period {
+	case "daily":
+		return now.AddDate(0, 0, -1), nil
+	case "weekly":
+		return now.AddDate(0, 0, -7), nil
+	case "monthly":
+		return now.AddDate(0, -1, 0), nil
+	case "6-month":
+		return now.AddDate(0, -6, 0), nil
+	case "yearly":
+		return now.AddDate(-1, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q: expected one of %s", period, strings.Join(Periods, ", "))
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"pm/models"
+	"strings"
+)
+
+const (
+	statsLanguages = 5
+	statsLatestPRs = 5
+)
+
+// StatsData is the value the README stats section template is executed
+// against. It adds ready-to-print Markdown fields to TemplateData.
+type StatsData struct {
+	TemplateData
+	PRsMerged    int
+	Commits      int
+	Reviews      int
+	TopLanguages string
+	LatestPRs    string
+}
+
+func NewStatsData(title string, data models.ReportData) StatsData {
+	td := NewTemplateData(title, data)
+	stats := StatsData{
+		TemplateData: td,
+		PRsMerged:    td.Metrics.PRsMerged,
+		Commits:      td.Metrics.Commits,
+		Reviews:      td.Metrics.Reviews,
+		TopLanguages: strings.Join(topLanguages(statsLanguages, td.Metrics.Languages), ", "),
+	}
+
+	var prs []models.PullRequest
+	for _, activity := range data.Repos {
+		prs = append(prs, activity.PullRequests...)
+	}
+	prs, _ = sortPRs("-merged", prs)
+	if len(prs) > statsLatestPRs {
+		prs = prs[:statsLatestPRs]
+	}
+	var lines []string
+	for _, pr := range prs {
+		lines = append(lines, fmt.Sprintf("- [%s](%s)", markdownEscaper.Replace(pr.Title), pr.HTMLURL))
+	}
+	stats.LatestPRs = strings.Join(lines, "\n")
+	return stats
+}
+
+var markdownEscaper = strings.NewReplacer("[", `\[`, "]", `\]`, "|", `\|`)
+
+// RenderStatsSection renders the README stats section with the template at
+// templatePath, or the built-in one.
+func RenderStatsSection(data models.ReportData, title, templatePath string) (string, error) {
+	tmpl, err := LoadTemplate(templatePath, "stats")
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, NewStatsData(title, data)); err != nil {
+		return "", fmt.Errorf("failed to render stats section: %w", err)
+	}
+	return b.String(), nil
+}
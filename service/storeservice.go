@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	githubclient "pm/client"
+	"pm/models"
+	"pm/store"
+	"time"
+)
+
+// SnapshotPeriod is the period of the snapshots materialized in the store.
+const SnapshotPeriod = "week"
+
+// StoreSource reads report data from the metrics store when the store covers
+// the period and was synced within maxAge, and otherwise syncs it first.
+type StoreSource struct {
+	store    *store.Store
+	token    string
+	maxAge   time.Duration
+	username string
+}
+
+func NewStoreSource(st *store.Store, token string, maxAge time.Duration) *StoreSource {
+	return &StoreSource{store: st, token: token, maxAge: maxAge}
+}
+
+func (s *StoreSource) Get(since time.Time) (models.ReportData, error) {
+	if s.username == "" {
+		username, err := githubclient.GetGitHubUsername(s.token)
+		if err != nil {
+			return models.ReportData{}, fmt.Errorf("could not retrieve GitHub username: %w", err)
+		}
+		s.username = username
+	}
+
+	coverage, err := s.store.Coverage()
+	if err != nil {
+		return models.ReportData{}, err
+	}
+	if coverage.Covers(s.username, since) && time.Since(coverage.FetchedAt) < s.maxAge {
+		return LoadReportData(s.store, s.username, since, coverage.FetchedAt.In(since.Location()))
+	}
+
+	result, err := Sync(s.store, s.token, s.username, since, nil)
+	if err != nil {
+		return models.ReportData{}, err
+	}
+	if result.Failed > 0 {
+		log.Printf("⚠️ %d repositories failed to sync, the report may be incomplete", result.Failed)
+	}
+	return LoadReportData(s.store, s.username, since, time.Now().In(since.Location()))
+}
+
+// Snapshots returns the weekly snapshots in the store, oldest first.
+func (s *StoreSource) Snapshots() ([]models.MetricsSnapshot, error) {
+	return s.store.Snapshots(SnapshotPeriod)
+}
+
+// LoadReportData rebuilds report data for username between since and until
+// from the store. It includes the repositories updated or active in that
+// range which pass the repo filter.
+func LoadReportData(st *store.Store, username string, since, until time.Time) (models.ReportData, error) {
+	data := models.ReportData{Username: username, Since: since, GeneratedAt: until}
+	activity, err := st.Activity(since, until)
+	if err != nil {
+		return data, err
+	}
+
+	for _, repo := range activity.Repos {
+		if githubclient.RepoAllowed(repo.Repo.FullName) && repoActive(repo, since, until) {
+			data.Repos = append(data.Repos, repo)
+		}
+	}
+	data.ReviewedPRs = activity.Reviews
+	data.Reviews = len(activity.Reviews)
+	return data, nil
+}
+
+// RefreshSnapshots computes the metrics of every complete week within
+// coverage and stores them as snapshots.
+func RefreshSnapshots(st *store.Store, coverage store.Coverage) error {
+	var snapshots []models.MetricsSnapshot
+	now := time.Now()
+	start := WeekStart(coverage.Since)
+	if start.Before(coverage.Since) {
+		start = start.AddDate(0, 0, 7)
+	}
+	for end := start.AddDate(0, 0, 7); !end.After(coverage.FetchedAt); start, end = end, end.AddDate(0, 0, 7) {
+		data, err := LoadReportData(st, coverage.Username, start, end)
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, models.MetricsSnapshot{
+			Period:     SnapshotPeriod,
+			Start:      start,
+			End:        end,
+			ComputedAt: now,
+			Metrics:    ComputeMetrics(data),
+		})
+	}
+	return st.PutSnapshots(snapshots)
+}
+
+// WeekStart returns midnight on the Monday of t's week.
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	githubclient "pm/client"
+	"pm/models"
+	"pm/store"
+	"strings"
+	"time"
+)
+
+// commitOverlap is how far before a repository's cursor commits are refetched:
+// GitHub filters commits by their own date, which can predate the push that
+// brought them in.
+const commitOverlap = 7 * 24 * time.Hour
+
+// reviewWindowDays is the span of each review search: the search API returns
+// at most 1000 results, so a long range is searched in windows.
+const reviewWindowDays = 30
+
+// SyncResult counts what a Sync fetched.
+type SyncResult struct {
+	Repos        int
+	Unchanged    int
+	Failed       int
+	PullRequests int
+	Commits      int
+	Reviews      int
+}
+
+// Sync brings the store up to date for username's activity since since. Each
+// repository, and the reviews, keep a cursor of their last successful sync:
+// only what changed after it is fetched, and repositories not pushed to since
+// are skipped. A repository whose cursor does not reach back to since is
+// fetched in full. Failed repositories are logged and keep their cursor, and
+// the store's coverage only advances when everything synced. Reviews are
+// searched a window at a time, each window moving their cursor. progress, if
+// set, is told of each repository and review window synced.
+func Sync(st *store.Store, token, username string, since time.Time, progress func(string)) (SyncResult, error) {
+	var result SyncResult
+	if progress == nil {
+		progress = func(string) {}
+	}
+	now := time.Now().In(since.Location())
+
+	coverage, err := st.Coverage()
+	if err != nil {
+		return result, err
+	}
+	if coverage.Username != "" && coverage.Username != username {
+		return result, fmt.Errorf("metrics store %s holds the activity of %s, not %s: use another store path", st.Path(), coverage.Username, username)
+	}
+
+	repos, err := githubclient.ListUserRepos(token)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch repos: %w", err)
+	}
+
+	// covered is the latest cursor start: every repository and the reviews
+	// reach back to it, which may be further than since.
+	var covered time.Time
+	cover := func(c store.Cursor) {
+		if c.Start.After(covered) {
+			covered = c.Start
+		}
+	}
+	for _, repo := range repos {
+		cursor, ok, err := st.Cursor(repo.FullName)
+		if err != nil {
+			return result, err
+		}
+		if !ok || since.Before(cursor.Start) {
+			cursor = store.Cursor{Name: repo.FullName, Start: since}
+		} else if !changedSince(repo, cursor.SyncedAt) {
+			// Still save the metadata, so stars and descriptions stay current.
+			if err := st.SaveReportData(models.ReportData{Repos: []models.RepoActivity{{Repo: repo}}}); err != nil {
+				return result, err
+			}
+			cover(cursor)
+			result.Unchanged++
+			continue
+		}
+
+		activity, err := syncRepo(token, username, repo, cursor)
+		if err != nil {
+			log.Printf("⚠️ Failed to sync %s: %v", repo.FullName, err)
+			result.Failed++
+			continue
+		}
+		if err := st.SaveReportData(models.ReportData{Repos: []models.RepoActivity{activity}}); err != nil {
+			return result, err
+		}
+		cursor.SyncedAt = now
+		if err := st.PutCursor(cursor); err != nil {
+			return result, err
+		}
+		cover(cursor)
+		result.Repos++
+		result.PullRequests += len(activity.PullRequests)
+		result.Commits += len(activity.Commits)
+		progress(fmt.Sprintf("🔄 %s: %d PRs, %d commits", repo.FullName, len(activity.PullRequests), len(activity.Commits)))
+	}
+
+	cursor, ok, err := st.Cursor(store.ReviewsCursor)
+	if err != nil {
+		return result, err
+	}
+	if !ok || since.Before(cursor.Start) {
+		cursor = store.Cursor{Name: store.ReviewsCursor, Start: since, SyncedAt: since}
+	}
+	if result.Reviews, err = syncReviews(st, token, username, &cursor, now, progress); err != nil {
+		log.Printf("⚠️ Failed to sync reviews: %v", err)
+		result.Failed++
+	} else {
+		cursor.SyncedAt = now
+		if err := st.PutCursor(cursor); err != nil {
+			return result, err
+		}
+		cover(cursor)
+	}
+
+	if result.Failed > 0 {
+		return result, nil
+	}
+	coverage = store.Coverage{Username: username, Since: covered.In(since.Location()), FetchedAt: now}
+	if err := st.SetCoverage(coverage); err != nil {
+		return result, err
+	}
+	return result, RefreshSnapshots(st, coverage)
+}
+
+// syncReviews fetches the pull requests username reviewed that were updated
+// from cursor.SyncedAt until now, a reviewWindow at a time, and saves each
+// window with the time of the user's first review, moving the cursor past it.
+// A window holding more pull requests than a search returns is halved until
+// they fit.
+func syncReviews(st *store.Store, token, username string, cursor *store.Cursor, now time.Time, progress func(string)) (int, error) {
+	n := 0
+	for start := cursor.SyncedAt; start.Before(now); start = cursor.SyncedAt {
+		end := start.AddDate(0, 0, reviewWindowDays)
+		if end.After(now) {
+			end = now
+		}
+		reviewed, total, err := githubclient.GetUserReviewedPRsBetween(token, username, start, end)
+		for err == nil && total > len(reviewed) && end.Sub(start) > time.Hour {
+			end = start.Add(end.Sub(start) / 2)
+			reviewed, total, err = githubclient.GetUserReviewedPRsBetween(token, username, start, end)
+		}
+		if err != nil {
+			return n, err
+		}
+		if total > len(reviewed) {
+			log.Printf("⚠️ Only %d of the %d pull requests reviewed and updated between %s and %s could be fetched", len(reviewed), total, start.Format(time.RFC3339), end.Format(time.RFC3339))
+		}
+
+		for i, pr := range reviewed {
+			_, repo, ok := strings.Cut(pr.RepositoryURL, "/repos/")
+			if !ok {
+				continue
+			}
+			if reviewed[i].ReviewedAt, err = githubclient.GetFirstReviewTime(token, repo, pr.Number, username); err != nil {
+				return n, err
+			}
+		}
+		if err := st.SaveReportData(models.ReportData{ReviewedPRs: reviewed}); err != nil {
+			return n, err
+		}
+		cursor.SyncedAt = end
+		if err := st.PutCursor(*cursor); err != nil {
+			return n, err
+		}
+		n += len(reviewed)
+		progress(fmt.Sprintf("🔎 Reviews updated %s to %s: %d PRs", start.Format("2006-01-02"), end.Format("2006-01-02"), len(reviewed)))
+	}
+	return n, nil
+}
+
+// changedSince reports whether repo may have new pull requests or commits
+// after t.
+func changedSince(repo models.GithubRepo, t time.Time) bool {
+	for _, ts := range []string{repo.PushedAt, repo.UpdatedAt} {
+		changed, err := time.Parse(time.RFC3339, ts)
+		if err != nil || changed.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncRepo fetches what changed in repo since its cursor, or everything since
+// the cursor's start when it never synced.
+func syncRepo(token, username string, repo models.GithubRepo, cursor store.Cursor) (models.RepoActivity, error) {
+	activity := models.RepoActivity{Repo: repo}
+	owner, name, ok := strings.Cut(repo.FullName, "/")
+	if !ok {
+		return activity, fmt.Errorf("invalid repository name %q", repo.FullName)
+	}
+	prsFrom, commitsFrom := cursor.Start, cursor.Start
+	if !cursor.SyncedAt.IsZero() {
+		prsFrom = cursor.SyncedAt
+		if commitsFrom = cursor.SyncedAt.Add(-commitOverlap); commitsFrom.Before(cursor.Start) {
+			commitsFrom = cursor.Start
+		}
+	}
+
+	langs, err := githubclient.GetRepoLanguages(token, owner, name)
+	if err != nil {
+		log.Printf("⚠️ Failed to fetch languages for %s: %v", repo.FullName, err)
+	} else {
+		activity.Languages = langs
+	}
+	if activity.PullRequests, err = githubclient.ListMergedPRsUpdatedSince(token, owner, name, prsFrom); err != nil {
+		return activity, err
+	}
+	if activity.Commits, err = githubclient.ListCommitsSince(token, owner, name, username, commitsFrom); err != nil {
+		return activity, err
+	}
+	return activity, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	githubclient "pm/client"
+	"pm/models"
+	"pm/store"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHub serves the endpoints Sync uses, recording the since of each
+// commit listing and the start of each review search window.
+type fakeGitHub struct {
+	mu           sync.Mutex
+	repos        []models.GithubRepo
+	failCommits  map[string]bool
+	failSearchAt time.Time
+	commitsSince map[string][]string
+	searchStarts []string
+}
+
+func newFakeGitHub(t *testing.T, repos ...string) *fakeGitHub {
+	f := &fakeGitHub{failCommits: map[string]bool{}, commitsSince: map[string][]string{}}
+	pushed := time.Now().AddDate(0, 0, -2).UTC().Format(time.RFC3339)
+	for _, name := range repos {
+		owner, repo, _ := strings.Cut(name, "/")
+		f.repos = append(f.repos, models.GithubRepo{Name: repo, FullName: name, Owner: models.GithubUser{Login: owner}, PushedAt: pushed, UpdatedAt: pushed})
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	githubclient.SetAPIURL(server.URL)
+	t.Cleanup(func() { githubclient.SetHost("") })
+	return f
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(v any) { json.NewEncoder(w).Encode(v) }
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/user/repos":
+		reply(f.repos)
+	case r.URL.Path == "/search/issues":
+		_, updated, _ := strings.Cut(r.URL.Query().Get("q"), "updated:")
+		start, _, _ := strings.Cut(updated, "..")
+		f.searchStarts = append(f.searchStarts, start)
+		if at, err := time.Parse(time.RFC3339, start); !f.failSearchAt.IsZero() && err == nil && !at.Before(f.failSearchAt) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		reply(map[string]any{"total_count": 0, "items": []any{}})
+	case len(parts) == 4 && parts[0] == "repos" && parts[3] == "commits":
+		name := parts[1] + "/" + parts[2]
+		f.commitsSince[name] = append(f.commitsSince[name], r.URL.Query().Get("since"))
+		if f.failCommits[name] {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		reply([]models.Commit{})
+	case len(parts) == 4 && parts[0] == "repos" && parts[3] == "languages":
+		reply(map[string]int{"Go": 100})
+	case len(parts) == 4 && parts[0] == "repos" && parts[3] == "pulls":
+		reply([]models.PullRequest{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// takeRequests returns and clears the recorded requests.
+func (f *fakeGitHub) takeRequests() (map[string][]string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	commits, searches := f.commitsSince, f.searchStarts
+	f.commitsSince, f.searchStarts = map[string][]string{}, nil
+	return commits, searches
+}
+
+func openTestStore(t *testing.T) *store.Store {
+	st, err := store.Open(filepath.Join(t.TempDir(), "metrics.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func TestSyncResumesAfterPartialSync(t *testing.T) {
+	gh := newFakeGitHub(t, "me/one", "me/two")
+	st := openTestStore(t)
+	since := time.Now().AddDate(0, 0, -75).Truncate(time.Second)
+
+	// me/two and the second review window fail.
+	gh.failCommits["me/two"] = true
+	gh.failSearchAt = since.AddDate(0, 0, reviewWindowDays)
+	result, err := Sync(st, "token", "me", since, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Repos != 1 || result.Failed != 2 {
+		t.Errorf("first sync synced %d repos with %d failures, want 1 and 2", result.Repos, result.Failed)
+	}
+	if coverage, _ := st.Coverage(); !coverage.FetchedAt.IsZero() {
+		t.Errorf("coverage advanced to %+v after a partial sync", coverage)
+	}
+	if _, ok, _ := st.Cursor("me/two"); ok {
+		t.Error("the failed repository kept a cursor")
+	}
+	one, ok, _ := st.Cursor("me/one")
+	if !ok || !one.Start.Equal(since) || one.SyncedAt.IsZero() {
+		t.Errorf("me/one cursor = %+v, ok %v, want one starting at %s", one, ok, since)
+	}
+	reviews, _, _ := st.Cursor(store.ReviewsCursor)
+	if !reviews.SyncedAt.Equal(gh.failSearchAt) {
+		t.Errorf("reviews cursor synced to %s, want the end of the first window %s", reviews.SyncedAt, gh.failSearchAt)
+	}
+	gh.takeRequests()
+
+	gh.failCommits["me/two"] = false
+	gh.failSearchAt = time.Time{}
+	result, err = Sync(st, "token", "me", since, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Repos != 1 || result.Unchanged != 1 || result.Failed != 0 {
+		t.Errorf("second sync = %+v, want me/two synced and me/one unchanged", result)
+	}
+	commits, searches := gh.takeRequests()
+	if got := commits["me/two"]; len(got) != 1 || got[0] != utc(since) {
+		t.Errorf("me/two commits fetched since %v, want [%s]", got, utc(since))
+	}
+	if got := commits["me/one"]; len(got) != 0 {
+		t.Errorf("unchanged me/one commits fetched since %v", got)
+	}
+	if len(searches) == 0 || searches[0] != utc(reviews.SyncedAt) {
+		t.Errorf("review search windows start at %v, want the first at %s", searches, utc(reviews.SyncedAt))
+	}
+	coverage, _ := st.Coverage()
+	if !coverage.Since.Equal(since) || coverage.FetchedAt.IsZero() || coverage.Username != "me" {
+		t.Errorf("coverage = %+v, want me since %s", coverage, since)
+	}
+}
+
+func TestSyncCoverage(t *testing.T) {
+	since := time.Now().AddDate(0, 0, -20).Truncate(time.Second)
+	tests := []struct {
+		name string
+		// next is the since of the second sync.
+		next         time.Time
+		wantCoverage time.Time
+		// wantCommits is the since of the second sync's commit listings, or
+		// "" when the repository is skipped.
+		wantCommits string
+	}{
+		{"same range skips unchanged repositories", since, since, ""},
+		{"shorter range keeps the earlier coverage", since.AddDate(0, 0, 5), since, ""},
+		{"longer range refetches in full", since.AddDate(0, 0, -5), since.AddDate(0, 0, -5), utc(since.AddDate(0, 0, -5))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := newFakeGitHub(t, "me/one")
+			st := openTestStore(t)
+			if _, err := Sync(st, "token", "me", since, nil); err != nil {
+				t.Fatal(err)
+			}
+			gh.takeRequests()
+
+			if _, err := Sync(st, "token", "me", tt.next, nil); err != nil {
+				t.Fatal(err)
+			}
+			commits, _ := gh.takeRequests()
+			if got := strings.Join(commits["me/one"], ","); got != tt.wantCommits {
+				t.Errorf("commits fetched since %q, want %q", got, tt.wantCommits)
+			}
+			coverage, _ := st.Coverage()
+			if !coverage.Since.Equal(tt.wantCoverage) {
+				t.Errorf("coverage since %s, want %s", coverage.Since, tt.wantCoverage)
+			}
+		})
+	}
+}
+
+func TestSyncRejectsAnotherUsersStore(t *testing.T) {
+	newFakeGitHub(t, "me/one")
+	st := openTestStore(t)
+	if err := st.SetCoverage(store.Coverage{Username: "someone"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(st, "token", "me", time.Now().AddDate(0, 0, -7), nil); err == nil {
+		t.Error("Sync into another user's store succeeded")
+	}
+}
+
+func TestSyncRepoFetchRange(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		syncedAt    time.Time
+		wantCommits time.Time
+	}{
+		{"never synced", time.Time{}, start},
+		{"synced long after the start overlaps a week", start.AddDate(0, 1, 0), start.AddDate(0, 1, -7)},
+		{"overlap stops at the start", start.AddDate(0, 0, 3), start},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := newFakeGitHub(t)
+			repo := models.GithubRepo{Name: "one", FullName: "me/one", Owner: models.GithubUser{Login: "me"}}
+			if _, err := syncRepo("token", "me", repo, store.Cursor{Name: "me/one", Start: start, SyncedAt: tt.syncedAt}); err != nil {
+				t.Fatal(err)
+			}
+			commits, _ := gh.takeRequests()
+			if got := commits["me/one"]; len(got) != 1 || got[0] != utc(tt.wantCommits) {
+				t.Errorf("commits fetched since %v, want %s", got, utc(tt.wantCommits))
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pm/models"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// TemplateData is the value report templates are executed against.
+type TemplateData struct {
+	Title string
+	models.ReportData
+	Metrics models.ReportMetrics
+	// Previous holds the previous equivalent period when comparing, or nil.
+	Previous *PreviousPeriod
+}
+
+// PreviousPeriod is the period a report is compared with.
+type PreviousPeriod struct {
+	Since   time.Time
+	Until   time.Time
+	Metrics models.ReportMetrics
+}
+
+func NewTemplateData(title string, data models.ReportData) TemplateData {
+	return TemplateData{Title: title, ReportData: data, Metrics: ComputeMetrics(data)}
+}
+
+// NewComparisonTemplateData is NewTemplateData for data compared with the
+// previous period.
+func NewComparisonTemplateData(title string, data, previous models.ReportData) TemplateData {
+	td := NewTemplateData(title, data)
+	td.Previous = &PreviousPeriod{Since: previous.Since, Until: previous.GeneratedAt, Metrics: ComputeMetrics(previous)}
+	return td
+}
+
+// Changes compares the metrics with the previous period, if any.
+func (td TemplateData) Changes() []MetricChange {
+	if td.Previous == nil {
+		return nil
+	}
+	return CompareMetrics(td.Metrics, td.Previous.Metrics)
+}
+
+var templateFuncs = template.FuncMap{
+	"duration":     formatDuration,
+	"hours":        func(d time.Duration) string { return fmt.Sprintf("%.1f", d.Hours()) },
+	"percent":      percent,
+	"truncate":     truncate,
+	"firstLine":    func(s string) string { line, _, _ := strings.Cut(s, "\n"); return line },
+	"join":         strings.Join,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"date":         func(t time.Time) string { return t.Format("2006-01-02") },
+	"add":          func(a, b int) int { return a + b },
+	"languages":    sortedLanguages,
+	"topLanguages": topLanguages,
+	"sortPRs":      sortPRs,
+	"sortRepos":    sortRepos,
+	"md":           markdownEscaper.Replace,
+	"change":       formatChange,
+}
+
+// LoadTemplate parses the template at path, or the named built-in template
+// when path is empty.
+func LoadTemplate(path, builtin string) (*template.Template, error) {
+	if path == "" {
+		name := builtin + ".tmpl"
+		return template.New(name).Funcs(templateFuncs).ParseFS(builtinTemplates, "templates/"+name)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+func RenderTemplate(tmpl *template.Template, data TemplateData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// RenderReport renders td with the template at templatePath, falling back to
+// the built-in detailed layout.
+func RenderReport(td TemplateData, templatePath string) (string, error) {
+	tmpl, err := LoadTemplate(templatePath, "detailed")
+	if err != nil {
+		return "", err
+	}
+	return RenderTemplate(tmpl, td)
+}
+
+// RenderSummary renders td with the built-in summary layout.
+func RenderSummary(td TemplateData) (string, error) {
+	tmpl, err := LoadTemplate("", "summary")
+	if err != nil {
+		return "", err
+	}
+	return RenderTemplate(tmpl, td)
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Minute).String()
+}
+
+// formatChange formats the change from previous to current, two ints or two
+// durations, as e.g. "(+3, +42.9%)", "(-1h30m0s, -25.0%)" or "(no change)".
+func formatChange(current, previous any) (string, error) {
+	var delta string
+	var c MetricChange
+	switch cur := current.(type) {
+	case int:
+		prev, ok := previous.(int)
+		if !ok {
+			return "", fmt.Errorf("change: cannot compare int with %T", previous)
+		}
+		c = MetricChange{Value: float64(cur), Previous: float64(prev)}
+		delta = fmt.Sprintf("%+d", cur-prev)
+	case time.Duration:
+		prev, ok := previous.(time.Duration)
+		if !ok {
+			return "", fmt.Errorf("change: cannot compare time.Duration with %T", previous)
+		}
+		c = MetricChange{Value: float64(cur), Previous: float64(prev)}
+		delta = formatDuration(cur - prev)
+		if cur > prev {
+			delta = "+" + delta
+		}
+	default:
+		return "", fmt.Errorf("change: cannot compare %T", current)
+	}
+	if c.Delta() == 0 {
+		return "(no change)", nil
+	}
+	if p, ok := c.Percent(); ok {
+		return fmt.Sprintf("(%s, %+.1f%%)", delta, p), nil
+	}
+	return "(" + delta + ")", nil
+}
+
+func percent(part, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
+
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// sortedLanguages returns language names ordered by bytes of code, largest first.
+func sortedLanguages(langs map[string]int) []string {
+	names := make([]string, 0, len(langs))
+	for lang := range langs {
+		names = append(names, lang)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if langs[names[i]] != langs[names[j]] {
+			return langs[names[i]] > langs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func topLanguages(n int, langs map[string]int) []string {
+	names := sortedLanguages(langs)
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// sortPRs orders pull requests by key (title, created, merged, additions,
+// deletions, changed). A leading "-" sorts descending.
+func sortPRs(key string, prs []models.PullRequest) ([]models.PullRequest, error) {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b models.PullRequest) bool
+	switch key {
+	case "title":
+		less = func(a, b models.PullRequest) bool { return a.Title < b.Title }
+	case "created":
+		less = func(a, b models.PullRequest) bool { return a.CreatedAt < b.CreatedAt }
+	case "merged":
+		less = func(a, b models.PullRequest) bool { return a.MergedAt < b.MergedAt }
+	case "additions":
+		less = func(a, b models.PullRequest) bool { return a.Additions < b.Additions }
+	case "deletions":
+		less = func(a, b models.PullRequest) bool { return a.Deletions < b.Deletions }
+	case "changed":
+		less = func(a, b models.PullRequest) bool { return a.ChangedFiles < b.ChangedFiles }
+	default:
+		return nil, fmt.Errorf("unknown PR sort key %q", key)
+	}
+
+	sorted := append([]models.PullRequest(nil), prs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if desc {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
+
+// sortRepos orders repositories by key (name, prs, commits, stars). A leading
+// "-" sorts descending.
+func sortRepos(key string, repos []models.RepoActivity) ([]models.RepoActivity, error) {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b models.RepoActivity) bool
+	switch key {
+	case "name":
+		less = func(a, b models.RepoActivity) bool { return a.Repo.FullName < b.Repo.FullName }
+	case "prs":
+		less = func(a, b models.RepoActivity) bool { return len(a.PullRequests) < len(b.PullRequests) }
+	case "commits":
+		less = func(a, b models.RepoActivity) bool { return len(a.Commits) < len(b.Commits) }
+	case "stars":
+		less = func(a, b models.RepoActivity) bool { return a.Repo.StargazersCount < b.Repo.StargazersCount }
+	default:
+		return nil, fmt.Errorf("unknown repo sort key %q", key)
+	}
+
+	sorted := append([]models.RepoActivity(nil), repos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if desc {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"pm/models"
+	"strings"
+	"time"
+)
+
+// TrendPeriods lists the periods trends can be drawn by: those the store
+// keeps snapshots of.
+var TrendPeriods = []string{SnapshotPeriod}
+
+const trendBarWidth = 30
+
+var (
+	sparkRunes   = []rune("▁▂▃▄▅▆▇█")
+	partialBlock = []rune(" ▏▎▍▌▋▊▉")
+)
+
+// TrendPoint is a metric's value over one period. Missing marks a period the
+// store holds no snapshot of; it is left out of the rolling average.
+type TrendPoint struct {
+	Start   time.Time
+	End     time.Time
+	Value   float64
+	Average float64
+	Missing bool
+}
+
+// WeeklyTrend returns metric over the last complete weeks before end, oldest
+// first, each with the average of the present values among the window
+// points ending at it.
+func WeeklyTrend(snapshots []models.MetricsSnapshot, metric string, end time.Time, last, window int) ([]TrendPoint, error) {
+	if _, ok := MetricValue(models.ReportMetrics{}, metric); !ok {
+		return nil, fmt.Errorf("unknown metric %q: expected one of %s", metric, strings.Join(MetricNames, ", "))
+	}
+	byStart := map[time.Time]models.ReportMetrics{}
+	for _, snapshot := range snapshots {
+		if snapshot.Period == SnapshotPeriod {
+			byStart[snapshot.Start.UTC()] = snapshot.Metrics
+		}
+	}
+
+	end = WeekStart(end)
+	points := make([]TrendPoint, last)
+	for i := range points {
+		start := end.AddDate(0, 0, -7*(last-i))
+		point := TrendPoint{Start: start, End: start.AddDate(0, 0, 7)}
+		metrics, ok := byStart[start.UTC()]
+		if ok {
+			point.Value, _ = MetricValue(metrics, metric)
+		}
+		point.Missing = !ok
+		points[i] = point
+	}
+
+	for i := range points {
+		sum, n := 0.0, 0
+		for j := max(0, i-window+1); j <= i; j++ {
+			if !points[j].Missing {
+				sum += points[j].Value
+				n++
+			}
+		}
+		if n > 0 {
+			points[i].Average = sum / float64(n)
+		}
+	}
+	return points, nil
+}
+
+// Sparkline draws the points as one line of block characters, scaled from
+// zero to the largest value. Missing points are blank.
+func Sparkline(points []TrendPoint) string {
+	top := trendMax(points)
+	var b strings.Builder
+	for _, p := range points {
+		switch {
+		case p.Missing:
+			b.WriteRune(' ')
+		case top == 0:
+			b.WriteRune(sparkRunes[0])
+		default:
+			b.WriteRune(sparkRunes[int(math.Round(p.Value/top*float64(len(sparkRunes)-1)))])
+		}
+	}
+	return b.String()
+}
+
+// FormatTrend renders the points of metric as a sparkline followed by one bar
+// per period with its value and rolling average.
+func FormatTrend(metric string, points []TrendPoint, window int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📈 %s per week, last %d weeks (rolling average over %d)\n", MetricLabel(metric), len(points), window)
+	b.WriteString(Sparkline(points) + "\n\n")
+
+	top := trendMax(points)
+	present, sum := 0, 0.0
+	for _, p := range points {
+		if p.Missing {
+			fmt.Fprintf(&b, "%s %-*s no data\n", p.Start.Format("2006-01-02"), trendBarWidth, "")
+			continue
+		}
+		present++
+		sum += p.Value
+		fmt.Fprintf(&b, "%s %-*s %8s  avg %s\n", p.Start.Format("2006-01-02"), trendBarWidth, bar(p.Value, top),
+			FormatMetricValue(metric, p.Value), FormatMetricValue(metric, p.Average))
+	}
+
+	if present == 0 {
+		b.WriteString("\nNo snapshots in this range: run `pm sync` or `pm backfill`.\n")
+		return b.String()
+	}
+	latest := points[len(points)-1]
+	fmt.Fprintf(&b, "\nMean %s per week over %d weeks with data", FormatMetricValue(metric, sum/float64(present)), present)
+	if !latest.Missing {
+		fmt.Fprintf(&b, "; last week %s against a rolling average of %s", FormatMetricValue(metric, latest.Value), FormatMetricValue(metric, latest.Average))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// bar draws value as a bar of trendBarWidth cells at top, in eighths of a cell.
+func bar(value, top float64) string {
+	if top == 0 {
+		return ""
+	}
+	eighths := int(math.Round(value / top * trendBarWidth * 8))
+	s := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		s += string(partialBlock[eighths%8])
+	}
+	return s
+}
+
+func trendMax(points []TrendPoint) float64 {
+	top := 0.0
+	for _, p := range points {
+		if !p.Missing && p.Value > top {
+			top = p.Value
+		}
+	}
+	return top
+}
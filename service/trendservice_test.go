@@ -0,0 +1,150 @@
+package service
+
+import (
+	"pm/models"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestWeekStart(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"monday midnight", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"sunday night", time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"after the spring forward", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), time.Date(2026, 3, 2, 0, 0, 0, 0, newYork)},
+		{"week after the spring forward", time.Date(2026, 3, 10, 12, 0, 0, 0, newYork), time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{"after the fall back", time.Date(2026, 11, 1, 12, 0, 0, 0, newYork), time.Date(2026, 10, 26, 0, 0, 0, 0, newYork)},
+		{"local monday is still sunday in UTC", time.Date(2026, 3, 9, 5, 0, 0, 0, tokyo), time.Date(2026, 3, 9, 0, 0, 0, 0, tokyo)},
+		{"across a year", time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeekStart(tt.t); !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("WeekStart(%s) = %s, want %s", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeeklyTrend(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	// snapshots returns weekly snapshots of prs_merged from the Monday first
+	// in loc, as RefreshSnapshots stores them and the store reads them back:
+	// in UTC. A negative value leaves the week out.
+	snapshots := func(first time.Time, values ...int) []models.MetricsSnapshot {
+		var out []models.MetricsSnapshot
+		for i, v := range values {
+			start := first.AddDate(0, 0, 7*i)
+			if v >= 0 {
+				out = append(out, models.MetricsSnapshot{
+					Period:  SnapshotPeriod,
+					Start:   start.UTC(),
+					End:     start.AddDate(0, 0, 7).UTC(),
+					Metrics: models.ReportMetrics{PRsMerged: v},
+				})
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []models.MetricsSnapshot
+		end       time.Time
+		last      int
+		window    int
+		wantStart time.Time
+		values    []float64
+		missing   []bool
+		averages  []float64
+	}{
+		{
+			name:      "weeks across the spring forward",
+			snapshots: snapshots(time.Date(2026, 2, 23, 0, 0, 0, 0, newYork), 1, 2, 3, 4),
+			end:       time.Date(2026, 3, 23, 9, 0, 0, 0, newYork),
+			last:      4,
+			window:    2,
+			wantStart: time.Date(2026, 2, 23, 0, 0, 0, 0, newYork),
+			values:    []float64{1, 2, 3, 4},
+			missing:   []bool{false, false, false, false},
+			averages:  []float64{1, 1.5, 2.5, 3.5},
+		},
+		{
+			name:      "weeks across the fall back",
+			snapshots: snapshots(time.Date(2026, 10, 19, 0, 0, 0, 0, berlin), 5, 6, 7),
+			end:       time.Date(2026, 11, 9, 0, 0, 0, 0, berlin),
+			last:      3,
+			window:    3,
+			wantStart: time.Date(2026, 10, 19, 0, 0, 0, 0, berlin),
+			values:    []float64{5, 6, 7},
+			missing:   []bool{false, false, false},
+			averages:  []float64{5, 5.5, 6},
+		},
+		{
+			name:      "the current week is left out and gaps are missing",
+			snapshots: snapshots(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), 2, -1, 4, 9),
+			end:       time.Date(2026, 3, 25, 12, 0, 0, 0, time.UTC),
+			last:      3,
+			window:    3,
+			wantStart: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			values:    []float64{2, 0, 4},
+			missing:   []bool{false, true, false},
+			averages:  []float64{2, 2, 3},
+		},
+		{
+			name:      "weeks of another time zone do not match",
+			snapshots: snapshots(time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), 1, 2),
+			end:       time.Date(2026, 3, 16, 0, 0, 0, 0, newYork),
+			last:      2,
+			window:    2,
+			wantStart: time.Date(2026, 3, 2, 0, 0, 0, 0, newYork),
+			values:    []float64{0, 0},
+			missing:   []bool{true, true},
+			averages:  []float64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := WeeklyTrend(tt.snapshots, "prs_merged", tt.end, tt.last, tt.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(points) != tt.last {
+				t.Fatalf("got %d points, want %d", len(points), tt.last)
+			}
+			start := tt.wantStart
+			for i, p := range points {
+				if !p.Start.Equal(start) || !p.End.Equal(start.AddDate(0, 0, 7)) {
+					t.Errorf("point %d spans %s to %s, want the week from %s", i, p.Start, p.End, start)
+				}
+				if p.Value != tt.values[i] || p.Missing != tt.missing[i] || p.Average != tt.averages[i] {
+					t.Errorf("point %d = %v (missing %v, average %v), want %v (missing %v, average %v)",
+						i, p.Value, p.Missing, p.Average, tt.values[i], tt.missing[i], tt.averages[i])
+				}
+				start = start.AddDate(0, 0, 7)
+			}
+		})
+	}
+}
+
+func TestWeeklyTrendUnknownMetric(t *testing.T) {
+	if _, err := WeeklyTrend(nil, "lines_of_code", time.Now(), 4, 2); err == nil {
+		t.Error("WeeklyTrend accepted an unknown metric")
+	}
+}
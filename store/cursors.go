@@ -0,0 +1,35 @@
+package store
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ReviewsCursor is the cursor name of the user's reviews. Repository names
+// always contain a slash, so it cannot clash with one.
+const ReviewsCursor = "reviews"
+
+// Cursor records how far a repository, or the reviews, have been synced:
+// everything since Start, up to SyncedAt, the start of the last successful
+// sync.
+type Cursor struct {
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// Cursor returns the cursor stored under name, if any.
+func (s *Store) Cursor(name string) (Cursor, bool, error) {
+	var c Cursor
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(bucketCursors), []byte(name), &c)
+	})
+	return c, c.Name != "", err
+}
+
+func (s *Store) PutCursor(c Cursor) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketCursors), []byte(c.Name), c)
+	})
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// migrations upgrade the schema one version at a time: migrations[i] moves a
+// store from version i to i+1. Append new ones; never change a released one.
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: initial buckets.
+	func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketRepos, bucketPulls, bucketCommits, bucketReviews, bucketSnapshots} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 2: per-repository sync cursors.
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketCursors)
+		return err
+	},
+}
+
+// SchemaVersion is the schema version this pm writes.
+var SchemaVersion = len(migrations)
+
+func schemaVersion(tx *bolt.Tx) (int, error) {
+	meta := tx.Bucket(bucketMeta)
+	if meta == nil {
+		return 0, nil
+	}
+	v := meta.Get(keySchemaVersion)
+	if v == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", v)
+	}
+	return version, nil
+}
+
+// migrate runs each pending migration in its own transaction, so a failure
+// leaves the store at the last version that completed.
+func (s *Store) migrate() error {
+	for {
+		done := false
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucketIfNotExists(bucketMeta)
+			if err != nil {
+				return err
+			}
+			version, err := schemaVersion(tx)
+			if err != nil {
+				return err
+			}
+			if version > SchemaVersion {
+				return fmt.Errorf("has schema version %d, this pm supports %d", version, SchemaVersion)
+			}
+			if version == SchemaVersion {
+				done = true
+				return nil
+			}
+			if err := migrations[version](tx); err != nil {
+				return fmt.Errorf("migration to version %d failed: %w", version+1, err)
+			}
+			return meta.Put(keySchemaVersion, []byte(strconv.Itoa(version+1)))
+		})
+		if err != nil {
+			return fmt.Errorf("metrics store %s %w", s.path, err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package store keeps fetched GitHub activity and computed metrics snapshots
+// in a local bbolt database, so reports can be built without refetching
+// everything and compared over time.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pm/models"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+var (
+	bucketMeta      = []byte("meta")
+	bucketRepos     = []byte("repos")
+	bucketPulls     = []byte("pulls")
+	bucketCommits   = []byte("commits")
+	bucketReviews   = []byte("reviews")
+	bucketSnapshots = []byte("snapshots")
+	bucketCursors   = []byte("cursors")
+
+	keySchemaVersion = []byte("schema_version")
+	keyCoverage      = []byte("coverage")
+)
+
+// Store is an open metrics store. Only one process can hold it open.
+type Store struct {
+	db   *bolt.DB
+	path string
+}
+
+// Coverage is the range the store holds complete activity for: everything
+// from Since up to FetchedAt, the last time it was collected.
+type Coverage struct {
+	Username  string    `json:"username"`
+	Since     time.Time `json:"since"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Covers reports whether the store holds activity for username since since.
+func (c Coverage) Covers(username string, since time.Time) bool {
+	return !c.FetchedAt.IsZero() && c.Username == username && !since.Before(c.Since)
+}
+
+// Activity is the stored activity within a time range.
+type Activity struct {
+	// Repos holds every stored repository, with only the pull requests merged
+	// and commits authored within the range.
+	Repos   []models.RepoActivity
+	Reviews []models.ReviewedPR
+}
+
+type repoRecord struct {
+	Repo      models.GithubRepo `json:"repo"`
+	Languages map[string]int    `json:"languages"`
+}
+
+// Open opens the store at path, creating it if needed, and migrates it to the
+// current schema.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create metrics store dir: %w", err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, berrors.ErrTimeout) {
+		return nil, fmt.Errorf("metrics store %s is in use by another pm process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metrics store %s: %w", path, err)
+	}
+
+	s := &Store{db: db, path: path}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// SchemaVersion returns the schema version the store is at.
+func (s *Store) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Coverage returns the range the store holds, or a zero Coverage.
+func (s *Store) Coverage() (Coverage, error) {
+	var c Coverage
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(bucketMeta), keyCoverage, &c)
+	})
+	return c, err
+}
+
+func (s *Store) SetCoverage(c Coverage) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(bucketMeta), keyCoverage, c)
+	})
+}
+
+// SaveReportData stores the repositories, pull requests, commits and reviews
+// in data, replacing earlier copies.
+func (s *Store) SaveReportData(data models.ReportData) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		repos, pulls, commits := tx.Bucket(bucketRepos), tx.Bucket(bucketPulls), tx.Bucket(bucketCommits)
+		for _, activity := range data.Repos {
+			name := activity.Repo.FullName
+			record := repoRecord{Repo: activity.Repo, Languages: activity.Languages}
+			if record.Languages == nil {
+				// Keep the languages of an earlier fetch when this one failed.
+				var old repoRecord
+				if err := getJSON(repos, []byte(name), &old); err != nil {
+					return err
+				}
+				record.Languages = old.Languages
+			}
+			if err := putJSON(repos, []byte(name), record); err != nil {
+				return err
+			}
+			for _, pr := range activity.PullRequests {
+				if err := putJSON(pulls, pullKey(name, pr.Number), pr); err != nil {
+					return err
+				}
+			}
+			for _, c := range activity.Commits {
+				if err := putJSON(commits, []byte(name+"@"+c.SHA), c); err != nil {
+					return err
+				}
+			}
+		}
+		reviews := tx.Bucket(bucketReviews)
+		for _, pr := range data.ReviewedPRs {
+			if err := putJSON(reviews, []byte(pr.HTMLURL), pr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Activity returns the stored activity between since and until.
+func (s *Store) Activity(since, until time.Time) (Activity, error) {
+	var a Activity
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRepos).ForEach(func(k, v []byte) error {
+			var record repoRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("repo %s: %w", k, err)
+			}
+			activity := models.RepoActivity{Repo: record.Repo, Languages: record.Languages}
+
+			prefix := []byte(record.Repo.FullName + "#")
+			err := forEachPrefix(tx.Bucket(bucketPulls), prefix, func(v []byte) error {
+				var pr models.PullRequest
+				if err := json.Unmarshal(v, &pr); err != nil {
+					return err
+				}
+				if inRange(pr.MergedAt, since, until) {
+					activity.PullRequests = append(activity.PullRequests, pr)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("pull requests of %s: %w", k, err)
+			}
+
+			prefix = []byte(record.Repo.FullName + "@")
+			err = forEachPrefix(tx.Bucket(bucketCommits), prefix, func(v []byte) error {
+				var c models.Commit
+				if err := json.Unmarshal(v, &c); err != nil {
+					return err
+				}
+				if inRange(c.Commit.Author.Date, since, until) {
+					activity.Commits = append(activity.Commits, c)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("commits of %s: %w", k, err)
+			}
+			a.Repos = append(a.Repos, activity)
+			return nil
+		})
+	})
+	if err != nil {
+		return a, fmt.Errorf("failed to read metrics store: %w", err)
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketReviews).ForEach(func(_, v []byte) error {
+			var pr models.ReviewedPR
+			if err := json.Unmarshal(v, &pr); err != nil {
+				return err
+			}
+			if inRange(pr.ReviewTime(), since, until) {
+				a.Reviews = append(a.Reviews, pr)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return a, fmt.Errorf("failed to read reviews from metrics store: %w", err)
+	}
+	return a, nil
+}
+
+// PutSnapshots stores snapshots, replacing any with the same period and start.
+func (s *Store) PutSnapshots(snapshots []models.MetricsSnapshot) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSnapshots)
+		for _, snapshot := range snapshots {
+			if err := putJSON(b, snapshotKey(snapshot.Period, snapshot.Start), snapshot); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Snapshots returns the stored snapshots of period, oldest first.
+func (s *Store) Snapshots(period string) ([]models.MetricsSnapshot, error) {
+	var snapshots []models.MetricsSnapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachPrefix(tx.Bucket(bucketSnapshots), []byte(period+"/"), func(v []byte) error {
+			var snapshot models.MetricsSnapshot
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Start.Before(snapshots[j].Start) })
+	return snapshots, nil
+}
+
+func pullKey(repo string, number int) []byte {
+	return []byte(fmt.Sprintf("%s#%010d", repo, number))
+}
+
+func snapshotKey(period string, start time.Time) []byte {
+	return []byte(period + "/" + start.UTC().Format(time.RFC3339))
+}
+
+// inRange reports whether the RFC 3339 timestamp ts is in [since, until).
+func inRange(ts string, since, until time.Time) bool {
+	t, err := time.Parse(time.RFC3339, ts)
+	return err == nil && !t.Before(since) && t.Before(until)
+}
+
+func forEachPrefix(b *bolt.Bucket, prefix []byte, fn func(v []byte) error) error {
+	c := b.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getJSON(b *bolt.Bucket, key []byte, v any) error {
+	content := b.Get(key)
+	if content == nil {
+		return nil
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return nil
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return b.Put(key, content)
+}
@@ -0,0 +1,121 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+type Style string
+
+const (
+	Flat       Style = "flat"
+	FlatSquare Style = "flat-square"
+)
+
+const (
+	fontFamily  = "Verdana,Geneva,DejaVu Sans,sans-serif"
+	fontSize    = 11
+	badgeHeight = 20
+	padding     = 5
+)
+
+// namedColors follows the shields.io palette so configs can keep using names.
+var namedColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"lightgrey":   "#9f9f9f",
+	"grey":        "#555",
+	"gray":        "#555",
+}
+
+// Color resolves a shields-style color name or bare hex value to a CSS color.
+func Color(color string) string {
+	if hex, ok := namedColors[strings.ToLower(color)]; ok {
+		return hex
+	}
+	if color == "" {
+		return namedColors["lightgrey"]
+	}
+	if strings.HasPrefix(color, "#") {
+		return color
+	}
+	return "#" + color
+}
+
+// Badge renders a two-part label/message badge.
+func Badge(label, message, color string, style Style) string {
+	labelWidth := int(TextWidth(label, fontSize)+0.5) + 2*padding
+	messageWidth := int(TextWidth(message, fontSize)+0.5) + 2*padding
+	width := labelWidth + messageWidth
+	title := html.EscapeString(label + ": " + message)
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	labelX := float64(labelWidth) / 2
+	messageX := float64(labelWidth) + float64(messageWidth)/2
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s">`, width, badgeHeight, title)
+	fmt.Fprintf(&b, `<title>%s</title>`, title)
+
+	if style == FlatSquare {
+		fmt.Fprintf(&b, `<g shape-rendering="crispEdges"><rect width="%d" height="%d" fill="#555"/><rect x="%d" width="%d" height="%d" fill="%s"/></g>`,
+			labelWidth, badgeHeight, labelWidth, messageWidth, badgeHeight, Color(color))
+		fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="%s" font-size="%d">`, fontFamily, fontSize)
+		fmt.Fprintf(&b, `<text x="%.1f" y="14">%s</text><text x="%.1f" y="14">%s</text></g>`, labelX, label, messageX, message)
+		b.WriteString("</svg>\n")
+		return b.String()
+	}
+
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="%d" rx="3" fill="#fff"/></clipPath>`, width, badgeHeight)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="%d" fill="#555"/><rect x="%d" width="%d" height="%d" fill="%s"/><rect width="%d" height="%d" fill="url(#s)"/></g>`,
+		labelWidth, badgeHeight, labelWidth, messageWidth, badgeHeight, Color(color), width, badgeHeight)
+	fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="%s" font-size="%d">`, fontFamily, fontSize)
+	for _, part := range []struct {
+		x    float64
+		text string
+	}{{labelX, label}, {messageX, message}} {
+		fmt.Fprintf(&b, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, part.x, part.text, part.x, part.text)
+	}
+	b.WriteString("</g></svg>\n")
+	return b.String()
+}
+
+// verdanaWidths are advance widths of Verdana at 11px for printable ASCII,
+// starting at ' '.
+var verdanaWidths = [...]float64{
+	3.87, 4.33, 5.05, 9.0, 7.0, 11.84, 7.99, 2.95, 4.99, 4.99, 7.0, 9.0, 4.0, 4.99, 4.0, 4.99, // space to /
+	7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, 7.0, // 0-9
+	4.99, 4.99, 9.0, 9.0, 9.0, 6.0, 11.0, // : to @
+	7.52, 7.54, 7.68, 8.48, 6.96, 6.32, 8.53, 8.27, 4.63, 4.99, 7.62, 6.12, 9.27, 8.23, 8.66, // A-O
+	6.63, 8.66, 7.65, 7.52, 6.78, 8.05, 7.52, 10.88, 7.54, 6.77, 7.54, // P-Z
+	4.99, 4.99, 4.99, 9.0, 7.0, 7.0, // [ to `
+	6.61, 6.85, 5.73, 6.85, 6.55, 3.87, 6.85, 6.96, 3.02, 3.79, 6.51, 3.02, 10.7, 6.96, 6.68, // a-o
+	6.85, 6.85, 4.69, 5.73, 4.33, 6.96, 6.51, 9.0, 6.51, 6.51, 5.78, // p-z
+	6.98, 4.99, 6.98, 9.0, // { to ~
+}
+
+// TextWidth estimates the rendered width in pixels of s in Verdana at size px.
+// Non-ASCII runes such as emoji are counted as one wide glyph.
+func TextWidth(s string, size float64) float64 {
+	width := 0.0
+	for len(s) > 0 {
+		r, n := utf8.DecodeRuneInString(s)
+		s = s[n:]
+		switch {
+		case r >= ' ' && r <= '~':
+			width += verdanaWidths[r-' ']
+		case r == '\uFE0F' || r == '\u200D':
+			// Variation selectors and joiners render with no width.
+		default:
+			width += 13
+		}
+	}
+	return width * size / fontSize
+}
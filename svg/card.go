@@ -0,0 +1,109 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Theme colors a stats card.
+type Theme struct {
+	Name       string
+	Background string
+	Border     string
+	Title      string
+	Text       string
+	Icon       string
+	Track      string
+}
+
+var (
+	LightTheme = Theme{Name: "light", Background: "#fffefe", Border: "#e4e2e2", Title: "#2f80ed", Text: "#434d58", Icon: "#4c71f2", Track: "#ddd"}
+	DarkTheme  = Theme{Name: "dark", Background: "#151515", Border: "#30363d", Title: "#58a6ff", Text: "#c9d1d9", Icon: "#79c0ff", Track: "#30363d"}
+)
+
+// Themes maps theme names to themes.
+var Themes = map[string]Theme{"light": LightTheme, "dark": DarkTheme}
+
+// LanguageShare is one segment of the languages bar.
+type LanguageShare struct {
+	Name    string
+	Percent float64
+	Color   string
+}
+
+// CardStats is everything a stats card shows.
+type CardStats struct {
+	Title         string
+	PRsMerged     int
+	Commits       int
+	Reviews       int
+	CurrentStreak int
+	LongestStreak int
+	Languages     []LanguageShare
+}
+
+const (
+	cardWidth   = 495
+	cardPadding = 25
+	rowHeight   = 25
+)
+
+// StatsCard renders stats as a github-readme-stats style card.
+func StatsCard(stats CardStats, theme Theme) string {
+	rows := []struct {
+		icon, label, value string
+	}{
+		{"🟢", "PRs merged", fmt.Sprint(stats.PRsMerged)},
+		{"🔢", "Commits", fmt.Sprint(stats.Commits)},
+		{"🔍", "Reviews", fmt.Sprint(stats.Reviews)},
+		{"🔥", "Streak", fmt.Sprintf("%d days (best %d)", stats.CurrentStreak, stats.LongestStreak)},
+	}
+
+	langTop := 55 + len(rows)*rowHeight + 10
+	height := langTop
+	if len(stats.Languages) > 0 {
+		legendRows := (len(stats.Languages) + 2) / 3
+		height = langTop + 30 + legendRows*20
+	}
+	height += 10
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		cardWidth, height, cardWidth, height, html.EscapeString(stats.Title))
+	fmt.Fprintf(&b, `<title>%s</title>`, html.EscapeString(stats.Title))
+	fmt.Fprintf(&b, `<rect x="0.5" y="0.5" rx="4.5" width="%d" height="%d" fill="%s" stroke="%s"/>`, cardWidth-1, height-1, theme.Background, theme.Border)
+	fmt.Fprintf(&b, `<g font-family="'Segoe UI',Ubuntu,%s">`, fontFamily)
+	fmt.Fprintf(&b, `<text x="%d" y="35" font-size="18" font-weight="600" fill="%s">%s</text>`, cardPadding, theme.Title, html.EscapeString(stats.Title))
+
+	for i, row := range rows {
+		y := 70 + i*rowHeight
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" fill="%s">%s</text>`, cardPadding, y, theme.Icon, row.icon)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" font-weight="600" fill="%s">%s:</text>`, cardPadding+25, y, theme.Text, row.label)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" font-weight="700" fill="%s">%s</text>`, cardPadding+170, y, theme.Text, html.EscapeString(row.value))
+	}
+
+	if len(stats.Languages) > 0 {
+		barWidth := float64(cardWidth - 2*cardPadding)
+		fmt.Fprintf(&b, `<clipPath id="bar"><rect x="%d" y="%d" width="%.0f" height="8" rx="4"/></clipPath>`, cardPadding, langTop, barWidth)
+		fmt.Fprintf(&b, `<g clip-path="url(#bar)"><rect x="%d" y="%d" width="%.0f" height="8" fill="%s"/>`, cardPadding, langTop, barWidth, theme.Track)
+		x := float64(cardPadding)
+		for _, lang := range stats.Languages {
+			w := barWidth * lang.Percent / 100
+			fmt.Fprintf(&b, `<rect x="%.2f" y="%d" width="%.2f" height="8" fill="%s"/>`, x, langTop, w, lang.Color)
+			x += w
+		}
+		b.WriteString(`</g>`)
+
+		colWidth := (cardWidth - 2*cardPadding) / 3
+		for i, lang := range stats.Languages {
+			lx := cardPadding + (i%3)*colWidth
+			ly := langTop + 30 + (i/3)*20
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="5" fill="%s"/>`, lx+5, ly-4, lang.Color)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" fill="%s">%s %.1f%%</text>`, lx+15, ly, theme.Text, html.EscapeString(lang.Name), lang.Percent)
+		}
+	}
+
+	b.WriteString("</g></svg>\n")
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ConfirmModel shows Summary and asks a yes/no question about the change in
+// Preview. Without a preview it only waits for a key press.
+type ConfirmModel struct {
+	Title     string
+	Summary   string
+	Preview   string
+	Confirmed bool
+}
+
+func (m ConfirmModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ConfirmModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.Preview == "" {
+		return m, tea.Quit
+	}
+
+	switch key.String() {
+	case "y", "Y", "enter":
+		m.Confirmed = true
+		return m, tea.Quit
+	case "n", "N", "q", "esc", "ctrl+c":
+		m.Confirmed = false
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m ConfirmModel) View() string {
+	if m.Preview == "" {
+		return fmt.Sprintf(`
+%s
+----------------------------
+%s
+
+Press any key to exit.
+`, m.Title, m.Summary)
+	}
+
+	return fmt.Sprintf(`
+%s
+----------------------------
+%s
+%s
+
+Apply these changes? (y/n)
+`, m.Title, m.Summary, m.Preview)
+}
+
+// Confirm shows summary and preview and returns whether the user accepted the
+// previewed change.
+func Confirm(title, summary, preview string) (bool, error) {
+	p := tea.NewProgram(ConfirmModel{Title: title, Summary: summary, Preview: preview})
+	finalModel, err := p.Run()
+	if err != nil {
+		return false, err
+	}
+	return finalModel.(ConfirmModel).Confirmed, nil
+}
@@ -4,23 +4,47 @@ import (
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
 	"log"
+	"pm/models"
+	"pm/service"
+	"slices"
+	"strings"
 	"time"
 )
 
+// trendWeeks and trendWindow size the trends tab.
+const (
+	trendWeeks  = 26
+	trendWindow = 4
+)
+
 type Model struct {
 	Summary         string
+	Changes         []service.MetricChange
+	Snapshots       []models.MetricsSnapshot
+	ShowTrends      bool
+	TrendMetric     int
 	ReportGenerated bool
 	AwaitLength     bool
 	Token           string
 	Period          string
 	Since           time.Time
 	Done            bool
+	// Now returns the current time in the profile's timezone, which report
+	// periods and trend weeks start in.
+	Now func() time.Time
 }
 
 func (m Model) Init() tea.Cmd {
 	return nil
 }
 
+func (m Model) now() time.Time {
+	if m.Now == nil {
+		return time.Now()
+	}
+	return m.Now()
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -29,26 +53,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.AwaitLength {
 			switch key {
 			case "1", "2", "3", "4", "5":
-				prefix := ""
-				var since time.Time
-				now := time.Now()
-				switch key {
-				case "1":
-					prefix = "daily"
-					since = now.AddDate(0, 0, -1)
-				case "2":
-					prefix = "weekly"
-					since = now.AddDate(0, 0, -7)
-				case "3":
-					prefix = "monthly"
-					since = now.AddDate(0, -1, 0)
-				case "4":
-					prefix = "6-month"
-					since = now.AddDate(0, -6, 0)
-				case "5":
-					prefix = "yearly"
-					since = now.AddDate(-1, 0, 0)
-				}
+				prefix := service.Periods[key[0]-'1']
+				since, _ := service.PeriodStart(prefix, m.now())
 				m.Period = prefix
 				m.Since = since
 				m.Done = true
@@ -58,16 +64,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.ShowTrends {
+			switch key {
+			case "left", "h":
+				m.TrendMetric = (m.TrendMetric + len(service.MetricNames) - 1) % len(service.MetricNames)
+				return m, nil
+			case "right", "l":
+				m.TrendMetric = (m.TrendMetric + 1) % len(service.MetricNames)
+				return m, nil
+			case "esc":
+				m.ShowTrends = false
+				return m, nil
+			}
+		}
+
 		switch key {
 		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "t":
+			m.ShowTrends = !m.ShowTrends
+			m.AwaitLength = false
+			return m, nil
 		case "g":
 			m.AwaitLength = true
+			m.ShowTrends = false
 			m.ReportGenerated = false
 			return m, nil
 		case "b":
 			m.Period = "badges"
-			m.Since = time.Now()
+			m.Since = m.now()
 			m.Done = true
 			return m, tea.Quit
 		}
@@ -77,6 +102,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) View() string {
+	if m.ShowTrends {
+		return m.trendsView()
+	}
+
 	reportMessage := ""
 	if m.ReportGenerated {
 		reportMessage = "\n📁 Report exported successfully"
@@ -97,12 +126,49 @@ Choose report period:
 	return fmt.Sprintf(`
 📊 GitHub Developer Metrics
 ----------------------------
-%s%s%s
+%s%s%s%s
 
 Press g to generate a report.
+Press t to view trends.
 Press b to view badges.
 Press q to quit.
-`, m.Summary, lengthPrompt, reportMessage)
+`, m.Summary, formatChanges(m.Changes), lengthPrompt, reportMessage)
+}
+
+func (m Model) trendsView() string {
+	metric := service.MetricNames[m.TrendMetric]
+	chart := "No weekly snapshots yet: enable the metrics store (store.enabled) and run `pm sync` or `pm backfill`."
+	if len(m.Snapshots) > 0 {
+		points, err := service.WeeklyTrend(m.Snapshots, metric, m.now(), trendWeeks, trendWindow)
+		if err != nil {
+			chart = "⚠️ " + err.Error()
+		} else {
+			chart = service.FormatTrend(metric, points, trendWindow)
+		}
+	}
+
+	return fmt.Sprintf(`
+📊 GitHub Developer Metrics · Trends (%d/%d)
+----------------------------
+%s
+Press ←/→ to change metric.
+Press t or esc to go back.
+Press q to quit.
+`, m.TrendMetric+1, len(service.MetricNames), chart)
+}
+
+// formatChanges lists the changes from the previous week, each with an up or
+// down indicator.
+func formatChanges(changes []service.MetricChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\n📈 Compared with the previous week:")
+	for _, c := range changes {
+		fmt.Fprintf(&b, "\n%s %-22s %s", c.Indicator(), c.Label(), c.String())
+	}
+	return b.String()
 }
 
 func Run(summary string) {
@@ -112,11 +178,18 @@ func Run(summary string) {
 	}
 }
 
-// RunWithTokenWithSummary initializes the TUI model with both the token and prebuilt summary.
-func RunWithTokenWithSummary(token string, summary string) (string, time.Time, bool) {
+// RunWithTokenWithSummary initializes the TUI model with the token, prebuilt
+// summary, its changes from the previous week, the weekly snapshots the
+// trends tab charts and the profile's clock.
+func RunWithTokenWithSummary(token string, summary string, changes []service.MetricChange, snapshots []models.MetricsSnapshot, now func() time.Time) (string, time.Time, bool) {
 	model := Model{
-		Token:   token,
-		Summary: summary,
+		Now:       now,
+		Token:     token,
+		Summary:   summary,
+		Changes:   changes,
+		Snapshots: snapshots,
+		// Open the trends tab on PRs merged, like pm trends.
+		TrendMetric: max(0, slices.Index(service.MetricNames, "prs_merged")),
 	}
 	p := tea.NewProgram(model)
 	finalModel, err := p.StartReturningModel()
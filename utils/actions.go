@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// AppendStepSummary appends Markdown to the job summary at
+// $GITHUB_STEP_SUMMARY. It does nothing when the variable is unset, e.g.
+// outside GitHub Actions.
+func AppendStepSummary(markdown string) error {
+	return appendToEnvFile("GITHUB_STEP_SUMMARY", strings.TrimRight(markdown, "\n")+"\n")
+}
+
+// SetOutputs writes step outputs to $GITHUB_OUTPUT, using a heredoc for
+// multiline values. It does nothing when the variable is unset.
+func SetOutputs(outputs map[string]string) error {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(outputs)) {
+		value := outputs[name]
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&b, "%s=%s\n", name, value)
+			continue
+		}
+		delimiter := heredocDelimiter()
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", name, delimiter, strings.TrimRight(value, "\n"), delimiter)
+	}
+	return appendToEnvFile("GITHUB_OUTPUT", b.String())
+}
+
+func appendToEnvFile(env, content string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open $%s: %w", env, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write $%s: %w", env, err)
+	}
+	return nil
+}
+
+func heredocDelimiter() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "pm_" + hex.EncodeToString(b)
+}
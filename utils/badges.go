@@ -1,21 +1,109 @@
 package utils
 
 import (
-	"os"
+	"sort"
 	"strings"
 )
 
-func AddBadgeToReadme(badgeMarkdown string, readmePath string) error {
-	content, err := os.ReadFile(readmePath)
-	if err != nil {
-		return err
+const (
+	BadgeBlockStart = "<!-- pm:badges:start -->"
+	BadgeBlockEnd   = "<!-- pm:badges:end -->"
+)
+
+// BadgeBlock returns the badges inside the managed block, one per line, and
+// whether the block exists.
+func BadgeBlock(content string) ([]string, bool) {
+	start, end, ok := findBadgeBlock(content)
+	if !ok {
+		return nil, false
+	}
+	var badges []string
+	for _, line := range strings.Split(content[start+len(BadgeBlockStart):end], "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			badges = append(badges, line)
+		}
 	}
+	return badges, true
+}
+
+// SetBadgeBlock regenerates the managed block with badges sorted and
+// deduplicated, leaving the rest of content untouched. When the block does not
+// exist yet it is appended, and any of the badges previously appended on their
+// own line outside it are moved in.
+func SetBadgeBlock(content string, badges []string) string {
+	block := renderBadgeBlock(badges)
 
-	if strings.Contains(string(content), badgeMarkdown) {
-		// Badge already exists
-		return nil
+	if start, end, ok := findBadgeBlock(content); ok {
+		return content[:start] + block + content[end+len(BadgeBlockEnd):]
 	}
 
-	newContent := string(content) + "\n\n" + badgeMarkdown + "\n"
-	return os.WriteFile(readmePath, []byte(newContent), 0644)
+	content = RemoveStandaloneLines(content, badges)
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return block + "\n"
+	}
+	return content + "\n\n" + block + "\n"
+}
+
+func renderBadgeBlock(badges []string) string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, badge := range badges {
+		badge = strings.TrimSpace(badge)
+		if badge == "" || seen[badge] {
+			continue
+		}
+		seen[badge] = true
+		unique = append(unique, badge)
+	}
+	sort.Strings(unique)
+
+	lines := append([]string{BadgeBlockStart}, unique...)
+	lines = append(lines, BadgeBlockEnd)
+	return strings.Join(lines, "\n")
+}
+
+func findBadgeBlock(content string) (int, int, bool) {
+	return findBlock(content, BadgeBlockStart, BadgeBlockEnd)
+}
+
+// findBlock returns the offsets of startMarker and the endMarker following it.
+func findBlock(content, startMarker, endMarker string) (int, int, bool) {
+	start := strings.Index(content, startMarker)
+	if start < 0 {
+		return 0, 0, false
+	}
+	end := strings.Index(content[start:], endMarker)
+	if end < 0 {
+		return 0, 0, false
+	}
+	return start, start + end, true
+}
+
+// RemoveStandaloneLines drops lines equal to one of remove, along with the
+// blank line before each. Lines in fenced code blocks are kept, since they
+// show badges rather than display them.
+func RemoveStandaloneLines(content string, remove []string) string {
+	drop := map[string]bool{}
+	for _, line := range remove {
+		drop[strings.TrimSpace(line)] = true
+	}
+
+	fenced := fencedRanges(content)
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	offset := 0
+	for _, line := range lines {
+		start := offset
+		offset += len(line) + 1
+		if !drop[strings.TrimSpace(line)] || inRanges(fenced, start) {
+			kept = append(kept, line)
+			continue
+		}
+		// Older versions separated each appended badge with a blank line.
+		if n := len(kept); n > 0 && strings.TrimSpace(kept[n-1]) == "" {
+			kept = kept[:n-1]
+		}
+	}
+	return strings.Join(kept, "\n")
 }
@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+func TestRemoveStandaloneLines(t *testing.T) {
+	badge := "![PRs](https://img.shields.io/badge/PRs-12-blue)"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "drops the badge and the blank line before it",
+			content: "# Me\n\n" + badge + "\nbye",
+			want:    "# Me\nbye",
+		},
+		{
+			name:    "drops badges with surrounding spaces",
+			content: "  " + badge + "  \nbye",
+			want:    "bye",
+		},
+		{
+			name:    "keeps badges inside backtick fences",
+			content: "# Me\n\n```md\n\n" + badge + "\n```\n",
+			want:    "# Me\n\n```md\n\n" + badge + "\n```\n",
+		},
+		{
+			name:    "keeps badges inside tilde fences",
+			content: "~~~\n" + badge + "\n~~~\n\n" + badge,
+			want:    "~~~\n" + badge + "\n~~~",
+		},
+		{
+			name:    "keeps badges inside an unclosed fence",
+			content: "```\n" + badge,
+			want:    "```\n" + badge,
+		},
+		{
+			name:    "keeps other lines",
+			content: "hello\nworld",
+			want:    "hello\nworld",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RemoveStandaloneLines(tt.content, []string{badge})
+			if got != tt.want {
+				t.Errorf("RemoveStandaloneLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// numbered returns n lines where line i is i x's, unless replace has an entry for i.
+func numbered(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		line, ok := replace[i]
+		if !ok {
+			line = strings.Repeat("x", i)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deleted file",
+			a:    "a\nb\n",
+			b:    "",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "change in the middle keeps three lines of context",
+			a:    numbered(9, nil),
+			b:    numbered(9, map[int]string{5: "five"}),
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n xx\n xxx\n xxxx\n-xxxxx\n+five\n xxxxxx\n xxxxxxx\n xxxxxxxx\n",
+		},
+		{
+			name: "insertion at the end",
+			a:    numbered(5, nil),
+			b:    numbered(5, nil) + "six\n",
+			want: "--- old\n+++ new\n@@ -3,3 +3,4 @@\n xxx\n xxxx\n xxxxx\n+six\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			a:    numbered(10, nil),
+			b:    numbered(10, map[int]string{2: "two", 8: "eight"}),
+			want: "--- old\n+++ new\n@@ -1,10 +1,10 @@\n x\n-xx\n+two\n xxx\n xxxx\n xxxxx\n xxxxxx\n xxxxxxx\n-xxxxxxxx\n+eight\n xxxxxxxxx\n xxxxxxxxxx\n",
+		},
+		{
+			name: "distant changes get separate hunks",
+			a:    numbered(12, nil),
+			b:    numbered(12, map[int]string{1: "one", 12: "twelve"}),
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-x\n+one\n xx\n xxx\n xxxx\n" +
+				"@@ -9,4 +9,4 @@\n xxxxxxxxx\n xxxxxxxxxx\n xxxxxxxxxxx\n-xxxxxxxxxxxx\n+twelve\n",
+		},
+		{
+			name: "removal shifts the new line numbers",
+			a:    numbered(12, nil),
+			b:    strings.Replace(numbered(12, nil), "x\n", "", 1),
+			want: "--- old\n+++ new\n@@ -1,4 +1,3 @@\n-x\n xx\n xxx\n xxxx\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("old", "new", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotGitRepo     = errors.New("not a git repository")
+	ErrWrongBranch    = errors.New("profile repo is not on the configured branch")
+	ErrDirtyTree      = errors.New("profile repo has uncommitted changes")
+	ErrGitInProgress  = errors.New("a merge, rebase or cherry-pick is in progress in the profile repo")
+	ErrRebaseConflict = errors.New("rebasing onto the remote branch hit conflicts; the rebase was aborted")
+)
+
+// GitError is a failed git command with what it printed to stderr.
+type GitError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *GitError) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("git %s: %s", strings.Join(e.Args, " "), msg)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// GitRepo commits pm's changes in a local clone of the profile repo and
+// pushes them to Remote.
+type GitRepo struct {
+	Path    string
+	Remote  string
+	Branch  string
+	Message string
+	// Sign signs commits, with SigningKey when set or git's user.signingkey.
+	Sign       bool
+	SigningKey string
+}
+
+// output runs git silently and returns its stdout.
+func (r GitRepo) output(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.Path}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), &GitError{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return stdout.String(), nil
+}
+
+// run runs a git command that changes the repo, echoing it first.
+func (r GitRepo) run(args ...string) error {
+	fmt.Println("🔧 Running: git -C", r.Path, strings.Join(args, " "))
+	_, err := r.output(args...)
+	return err
+}
+
+func (r GitRepo) commitArgs() []string {
+	args := []string{"commit", "-m", r.Message}
+	if r.Sign {
+		args = append(args, "-S"+r.SigningKey)
+	}
+	return args
+}
+
+// PushCommands lists the git commands CommitAndPush runs.
+func (r GitRepo) PushCommands(files []string) [][]string {
+	var commands [][]string
+	if len(files) > 0 {
+		commands = append(commands, append([]string{"add", "--"}, files...), r.commitArgs())
+	}
+	return r.prefix(append(commands,
+		[]string{"fetch", r.Remote, r.Branch},
+		[]string{"rebase", "FETCH_HEAD"},
+		[]string{"push", r.Remote, "HEAD:" + r.Branch},
+	))
+}
+
+// BranchCommands lists the git commands CommitToBranch runs.
+func (r GitRepo) BranchCommands(files []string, branch string) [][]string {
+	return r.prefix([][]string{
+		{"checkout", "-B", branch},
+		append([]string{"add", "--"}, files...),
+		r.commitArgs(),
+		{"push", "--force-with-lease", "-u", r.Remote, branch},
+		{"checkout", r.Branch},
+	})
+}
+
+func (r GitRepo) prefix(commands [][]string) [][]string {
+	for i, args := range commands {
+		commands[i] = append([]string{"git", "-C", r.Path}, args...)
+	}
+	return commands
+}
+
+// Check verifies the repo is on Branch with no operation in progress and no
+// uncommitted changes other than to files, which pm is about to write.
+// Untracked files are ignored.
+func (r GitRepo) Check(files []string) error {
+	if _, err := r.output("rev-parse", "--git-dir"); err != nil {
+		return fmt.Errorf("%s: %w", r.Path, ErrNotGitRepo)
+	}
+
+	branch, err := r.output("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return fmt.Errorf("%w: HEAD is detached, expected %s", ErrWrongBranch, r.Branch)
+	}
+	if branch = strings.TrimSpace(branch); branch != r.Branch {
+		return fmt.Errorf("%w: on %s, expected %s", ErrWrongBranch, branch, r.Branch)
+	}
+
+	for _, marker := range []string{"MERGE_HEAD", "rebase-merge", "rebase-apply", "CHERRY_PICK_HEAD"} {
+		path, err := r.output("rev-parse", "--git-path", marker)
+		if err != nil {
+			return err
+		}
+		if path = strings.TrimSpace(path); !filepath.IsAbs(path) {
+			path = filepath.Join(r.Path, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return ErrGitInProgress
+		}
+	}
+
+	status, err := r.output("status", "--porcelain", "-z")
+	if err != nil {
+		return err
+	}
+	var dirty []string
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 || entry[0] == '?' {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // the source path of a rename or copy follows
+		}
+		if path := entry[3:]; !slices.Contains(files, filepath.FromSlash(path)) {
+			dirty = append(dirty, path)
+		}
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("%w: %s", ErrDirtyTree, strings.Join(dirty, ", "))
+	}
+	return nil
+}
+
+// Unpushed counts the commits on HEAD that <Remote>/<Branch> does not have,
+// as left by a failed rebase or push. It is zero when the remote branch was
+// never fetched.
+func (r GitRepo) Unpushed() (int, error) {
+	upstream := r.Remote + "/" + r.Branch
+	if _, err := r.output("rev-parse", "--verify", "--quiet", "refs/remotes/"+upstream); err != nil {
+		return 0, nil
+	}
+	out, err := r.output("rev-list", "--count", upstream+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// stage adds files and reports whether that changed anything to commit.
+func (r GitRepo) stage(files []string) (bool, error) {
+	if len(files) == 0 {
+		return false, nil
+	}
+	if err := r.run(append([]string{"add", "--"}, files...)...); err != nil {
+		return false, err
+	}
+	_, err := r.output(append([]string{"diff", "--cached", "--quiet", "--"}, files...)...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// CommitAndPush commits files on Branch, rebases onto the remote branch and
+// pushes. Commits an earlier run left unpushed, after a failed rebase or
+// push, are pushed too, even when files are unchanged. It reports false when
+// there was nothing to push.
+func (r GitRepo) CommitAndPush(files []string) (bool, error) {
+	changed, err := r.stage(files)
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		if err := r.run(r.commitArgs()...); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.run("fetch", r.Remote, r.Branch); err != nil {
+		var gitErr *GitError
+		// A brand new remote has no branch to rebase onto yet.
+		if !errors.As(err, &gitErr) || !strings.Contains(gitErr.Stderr, "couldn't find remote ref") {
+			return changed, err
+		}
+	} else {
+		ahead, err := r.output("rev-list", "--count", "FETCH_HEAD..HEAD")
+		if err != nil {
+			return changed, err
+		}
+		if strings.TrimSpace(ahead) == "0" {
+			return false, nil
+		}
+		if err := r.run("rebase", "FETCH_HEAD"); err != nil {
+			r.output("rebase", "--abort")
+			return true, fmt.Errorf("%w: %v", ErrRebaseConflict, err)
+		}
+	}
+	return true, r.run("push", r.Remote, "HEAD:"+r.Branch)
+}
+
+// CommitToBranch commits files on branch, created from the current HEAD,
+// pushes it and checks Branch out again. It reports false without committing
+// when files are unchanged.
+func (r GitRepo) CommitToBranch(files []string, branch string) (committed bool, err error) {
+	if err := r.run("checkout", "-B", branch); err != nil {
+		return false, err
+	}
+	defer func() {
+		if checkoutErr := r.run("checkout", r.Branch); err == nil {
+			err = checkoutErr
+		}
+	}()
+
+	changed, err := r.stage(files)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := r.run(r.commitArgs()...); err != nil {
+		return false, err
+	}
+	return true, r.run("push", "--force-with-lease", "-u", r.Remote, branch)
+}
+
+var remoteURL = regexp.MustCompile(`[:/]([^/:]+)/([^/]+?)(?:\.git)?/?$`)
+
+// RemoteRepo returns the owner/name of the GitHub repository Remote points at.
+func (r GitRepo) RemoteRepo() (string, error) {
+	out, err := r.output("remote", "get-url", r.Remote)
+	if err != nil {
+		return "", err
+	}
+	m := remoteURL.FindStringSubmatch(strings.TrimSpace(out))
+	if m == nil {
+		return "", fmt.Errorf("cannot tell the repository from remote %s URL %q", r.Remote, strings.TrimSpace(out))
+	}
+	return m[1] + "/" + m[2], nil
+}
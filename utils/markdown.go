@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Image is an image found in Markdown, written either as ![alt](src) or as an
+// HTML <img> tag.
+type Image struct {
+	Alt   string
+	Src   string
+	Title string
+	// Attrs holds the attributes of an <img> tag, with lowercase names. It is
+	// nil for Markdown images.
+	Attrs map[string]string
+	Raw   string
+	Line  int
+
+	offset int
+}
+
+var (
+	markdownImage = regexp.MustCompile(`!\[((?:[^\]\\]|\\.)*)\]\(\s*(<[^>]*>|[^\s)]+)(?:\s+(?:"([^"]*)"|'([^']*)'))?\s*\)`)
+	htmlImage     = regexp.MustCompile(`(?is)<img\b([^>]*?)/?>`)
+	htmlAttr      = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	markdownEsc   = regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`)
+)
+
+// ParseImages returns every image in content in document order, skipping
+// fenced code blocks.
+func ParseImages(content string) []Image {
+	fenced := fencedRanges(content)
+	inFence := func(offset int) bool { return inRanges(fenced, offset) }
+
+	var images []Image
+	for _, m := range markdownImage.FindAllStringSubmatchIndex(content, -1) {
+		if inFence(m[0]) {
+			continue
+		}
+		img := Image{
+			Alt:    markdownEsc.ReplaceAllString(content[m[2]:m[3]], "$1"),
+			Src:    strings.Trim(content[m[4]:m[5]], "<>"),
+			Raw:    content[m[0]:m[1]],
+			Line:   strings.Count(content[:m[0]], "\n") + 1,
+			offset: m[0],
+		}
+		for _, g := range []int{6, 8} {
+			if m[g] >= 0 {
+				img.Title = content[m[g]:m[g+1]]
+			}
+		}
+		images = append(images, img)
+	}
+
+	for _, m := range htmlImage.FindAllStringSubmatchIndex(content, -1) {
+		if inFence(m[0]) {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, a := range htmlAttr.FindAllStringSubmatch(content[m[2]:m[3]], -1) {
+			value := a[2] + a[3] + a[4]
+			attrs[strings.ToLower(a[1])] = html.UnescapeString(value)
+		}
+		images = append(images, Image{
+			Alt:    attrs["alt"],
+			Src:    attrs["src"],
+			Title:  attrs["title"],
+			Attrs:  attrs,
+			Raw:    content[m[0]:m[1]],
+			Line:   strings.Count(content[:m[0]], "\n") + 1,
+			offset: m[0],
+		})
+	}
+
+	sort.SliceStable(images, func(i, j int) bool { return images[i].offset < images[j].offset })
+	return images
+}
+
+// fencedRanges returns the byte ranges of ``` and ~~~ code blocks.
+func fencedRanges(content string) [][2]int {
+	var ranges [][2]int
+	fence, start, offset := "", 0, 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence, start = trimmed[:3], offset
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			ranges = append(ranges, [2]int{start, offset + len(line)})
+			fence = ""
+		}
+		offset += len(line)
+	}
+	if fence != "" {
+		ranges = append(ranges, [2]int{start, len(content)})
+	}
+	return ranges
+}
+
+// inRanges reports whether offset falls in one of ranges.
+func inRanges(ranges [][2]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImages(t *testing.T) {
+	type image struct {
+		Alt, Src, Title string
+		Line            int
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []image
+	}{
+		{
+			name:    "markdown image",
+			content: "# Hi\n![PRs](https://img.shields.io/badge/PRs-12-blue)",
+			want:    []image{{Alt: "PRs", Src: "https://img.shields.io/badge/PRs-12-blue", Line: 2}},
+		},
+		{
+			name:    "title, angle brackets and escaped alt",
+			content: `![a \] b](<badges/x.svg> "X badge")`,
+			want:    []image{{Alt: "a ] b", Src: "badges/x.svg", Title: "X badge", Line: 1}},
+		},
+		{
+			name:    "html image with entities",
+			content: `<img alt="Tom &amp; Jerry" SRC='badges/tj.svg' title=tj>`,
+			want:    []image{{Alt: "Tom & Jerry", Src: "badges/tj.svg", Title: "tj", Line: 1}},
+		},
+		{
+			name:    "document order across both kinds",
+			content: "<img src=\"b.svg\"> ![a](a.svg)\n![c](c.svg)",
+			want:    []image{{Src: "b.svg", Line: 1}, {Alt: "a", Src: "a.svg", Line: 1}, {Alt: "c", Src: "c.svg", Line: 2}},
+		},
+		{
+			name:    "fenced code blocks are skipped",
+			content: "```\n![a](a.svg)\n```\n~~~md\n<img src=\"b.svg\">\n~~~\n![c](c.svg)",
+			want:    []image{{Alt: "c", Src: "c.svg", Line: 7}},
+		},
+		{
+			name:    "an unclosed fence runs to the end",
+			content: "![a](a.svg)\n```\n![b](b.svg)",
+			want:    []image{{Alt: "a", Src: "a.svg", Line: 1}},
+		},
+		{
+			name:    "links are not images",
+			content: "[PRs](https://example.com)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []image
+			for _, img := range ParseImages(tt.content) {
+				got = append(got, image{img.Alt, img.Src, img.Title, img.Line})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseImages() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "strings"
+
+const (
+	// StatsPlaceholder marks where the stats section goes the first time.
+	StatsPlaceholder = "<!-- pm:stats -->"
+	StatsBlockStart  = "<!-- pm:stats:start -->"
+	StatsBlockEnd    = "<!-- pm:stats:end -->"
+)
+
+// SetStatsBlock replaces the managed stats section with body, leaving the rest
+// of content untouched. Without a section, the first StatsPlaceholder is
+// expanded into one, or the section is appended.
+func SetStatsBlock(content, body string) string {
+	block := StatsBlockStart + "\n" + strings.Trim(body, "\n") + "\n" + StatsBlockEnd
+
+	if start, end, ok := findBlock(content, StatsBlockStart, StatsBlockEnd); ok {
+		return content[:start] + block + content[end+len(StatsBlockEnd):]
+	}
+	if strings.Contains(content, StatsPlaceholder) {
+		return strings.Replace(content, StatsPlaceholder, block, 1)
+	}
+
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return block + "\n"
+	}
+	return content + "\n\n" + block + "\n"
+}